@@ -0,0 +1,111 @@
+package jsonrpcf
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Amount is a coin amount in satoshis (1e-8 of coin). It's encoded as
+// JSON number with 8 decimals (like 0.00010000) and decoded from JSON
+// number or string exactly, without float64 rounding errors.
+type Amount int64
+
+// Units of Amount.
+const (
+	Satoshi Amount = 1
+	Coin    Amount = 1e8
+)
+
+// ParseAmount parses decimal amount in coins (like "0.0001" or "1e-4").
+// It fails if amount has more than 8 decimals or doesn't fit in Amount.
+func ParseAmount(s string) (Amount, error) {
+	str, neg := s, false
+	if str != "" && (str[0] == '-' || str[0] == '+') {
+		str, neg = str[1:], str[0] == '-'
+	}
+	exp := 0
+	if i := strings.IndexAny(str, "eE"); i >= 0 {
+		var err error
+		exp, err = strconv.Atoi(str[i+1:])
+		if err != nil || exp < -100 || exp > 100 {
+			return 0, errors.New("bad amount: " + s)
+		}
+		str = str[:i]
+	}
+	intPart, fracPart := str, ""
+	if i := strings.IndexByte(str, '.'); i >= 0 {
+		intPart, fracPart = str[:i], str[i+1:]
+	}
+	if intPart == "" && fracPart == "" || !isDigits(intPart) || !isDigits(fracPart) {
+		return 0, errors.New("bad amount: " + s)
+	}
+
+	// Amount in satoshis is digits * 10^shift.
+	digits := strings.TrimLeft(intPart+fracPart, "0")
+	shift := exp - len(fracPart) + 8
+	if digits == "" {
+		return 0, nil
+	}
+	if shift < 0 {
+		n := len(digits) + shift
+		if n < 0 || strings.TrimRight(digits[n:], "0") != "" {
+			return 0, errors.New("amount has more than 8 decimals: " + s)
+		}
+		digits, shift = digits[:n], 0
+	}
+	if len(digits)+shift > 19 {
+		return 0, errors.New("amount out of range: " + s)
+	}
+	u, err := strconv.ParseUint(digits+strings.Repeat("0", shift), 10, 64)
+	switch {
+	case err != nil, !neg && u > math.MaxInt64, neg && u > -math.MinInt64:
+		return 0, errors.New("amount out of range: " + s)
+	case neg:
+		return Amount(-int64(u)), nil // also works for math.MinInt64
+	}
+	return Amount(u), nil
+}
+
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// String returns amount in coins with 8 decimals, like "-1.50000000".
+func (a Amount) String() string {
+	sign := ""
+	u := uint64(a)
+	if a < 0 {
+		sign, u = "-", uint64(-a) // also works for math.MinInt64
+	}
+	frac := strconv.FormatUint(u%uint64(Coin), 10)
+	return sign + strconv.FormatUint(u/uint64(Coin), 10) + "." + strings.Repeat("0", 8-len(frac)) + frac
+}
+
+// MarshalJSON implements json.Marshaler.
+func (a Amount) MarshalJSON() ([]byte, error) {
+	return []byte(a.String()), nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (a *Amount) UnmarshalJSON(data []byte) error {
+	s := string(data)
+	if s == "null" {
+		return nil
+	}
+	if len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"' {
+		s = s[1 : len(s)-1]
+	}
+	v, err := ParseAmount(s)
+	if err != nil {
+		return err
+	}
+	*a = v
+	return nil
+}
@@ -0,0 +1,103 @@
+package jsonrpcf
+
+import (
+	"encoding/json"
+	"math"
+	"net"
+	"testing"
+)
+
+func TestParseAmount(t *testing.T) {
+	cases := []struct {
+		in   string
+		want Amount
+		err  bool
+	}{
+		{"0", 0, false},
+		{"1", Coin, false},
+		{"-1.5", -150000000, false},
+		{"+0.00000001", Satoshi, false},
+		{"0.10000000000", 10000000, false},
+		{"21000000.00000000", 21000000 * Coin, false},
+		{"1e-8", Satoshi, false},
+		{"1.5E2", 150 * Coin, false},
+		{"0e-200", 0, true},
+		{"0e-20", 0, false},
+		{"92233720368.54775807", math.MaxInt64, false},
+		{"-92233720368.54775808", math.MinInt64, false},
+		{"92233720368.54775808", 0, true},
+		{"0.000000001", 0, true},
+		{"1e-9", 0, true},
+		{"1e100", 0, true},
+		{"", 0, true},
+		{".", 0, true},
+		{"-", 0, true},
+		{"0x10", 0, true},
+		{"1/2", 0, true},
+		{"1e", 0, true},
+	}
+	for _, c := range cases {
+		got, err := ParseAmount(c.in)
+		if got != c.want || (err != nil) != c.err {
+			t.Errorf("ParseAmount(%q) = %d, %v, want %d, error %v", c.in, got, err, c.want, c.err)
+		}
+	}
+}
+
+func TestAmountJSON(t *testing.T) {
+	cases := []struct {
+		a    Amount
+		want string
+	}{
+		{0, "0.00000000"},
+		{Satoshi, "0.00000001"},
+		{-150000000, "-1.50000000"},
+		{21000000 * Coin, "21000000.00000000"},
+		{math.MaxInt64, "92233720368.54775807"},
+		{math.MinInt64, "-92233720368.54775808"},
+	}
+	for _, c := range cases {
+		buf, err := json.Marshal(c.a)
+		if err != nil || string(buf) != c.want {
+			t.Errorf("Marshal(%d) = %s, %v, want %s", c.a, buf, err, c.want)
+		}
+		var got Amount
+		if err := json.Unmarshal(buf, &got); err != nil || got != c.a {
+			t.Errorf("Unmarshal(%s) = %d, %v, want %d", buf, got, err, c.a)
+		}
+	}
+
+	var v struct{ A, B Amount }
+	if err := json.Unmarshal([]byte(`{"A":"0.1","B":null}`), &v); err != nil || v.A != 10000000 || v.B != 0 {
+		t.Errorf("Unmarshal() = %+v, %v", v, err)
+	}
+	if err := json.Unmarshal([]byte(`{"A":true}`), &v); err == nil {
+		t.Errorf("Unmarshal(true), err = nil")
+	}
+}
+
+func TestClientUseNumber(t *testing.T) {
+	cli, srv := net.Pipe()
+	go ServeConn(srv)
+	client := NewClient(cli, WithUseNumber())
+	defer client.Close()
+
+	const big = 1<<53 + 1
+	var got interface{}
+	if err := client.Call("Svc.SumAll", []int{big, 0}, &got); err != nil {
+		t.Fatalf("Call(), err = %v", err)
+	}
+	if n, ok := got.(json.Number); !ok || n.String() != "9007199254740993" {
+		t.Errorf("Call() = %#v, want json.Number(9007199254740993)", got)
+	}
+
+	got = nil
+	b := client.Batch()
+	b.Call("Svc.SumAll", []int{big, 0}, &got)
+	if err := b.Send(); err != nil {
+		t.Fatalf("Batch.Send(), err = %v", err)
+	}
+	if n, ok := got.(json.Number); !ok || n.String() != "9007199254740993" {
+		t.Errorf("Batch.Call() = %#v, want json.Number(9007199254740993)", got)
+	}
+}
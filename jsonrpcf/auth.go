@@ -0,0 +1,92 @@
+package jsonrpcf
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// WithBasicAuth makes HTTP client authenticate using given user and
+// password (like rpcuser and rpcpassword of bitcoind-family nodes). It
+// replaces authentication set by WithCookieFile.
+func WithBasicAuth(user, password string) ClientOption {
+	return func(cfg *clientConfig) {
+		cfg.basicAuth = &[2]string{user, password}
+		cfg.cookie = nil
+	}
+}
+
+// WithCookieFile makes HTTP client authenticate using user and password
+// read from cookie file at path (like ~/.bitcoin/.cookie), which is
+// created by bitcoind family nodes on each start.
+//
+// File is read before first request and read again when server replied
+// with HTTP 401 Unauthorized, in which case request will be sent again
+// once if file has changed. So node's restart won't require restarting
+// client. It replaces authentication set by WithBasicAuth.
+func WithCookieFile(path string) ClientOption {
+	return func(cfg *clientConfig) {
+		cfg.cookie = &cookieAuth{path: path}
+		cfg.basicAuth = nil
+	}
+}
+
+// cookieAuth provides HTTP Basic auth using cookie file.
+type cookieAuth struct {
+	path string
+
+	mu     sync.Mutex // protects following
+	cookie string     // "user:password"
+	err    error      // error from last reading of file
+	loaded bool
+}
+
+// load reads cookie file. It must be called with a.mu locked.
+func (a *cookieAuth) load() {
+	a.loaded = true
+	buf, err := ioutil.ReadFile(a.path)
+	if err != nil {
+		a.err = err
+		return
+	}
+	cookie := strings.TrimSpace(string(buf))
+	if !strings.Contains(cookie, ":") {
+		a.err = errors.New("bad cookie file: " + a.path)
+		return
+	}
+	a.cookie, a.err = cookie, nil
+}
+
+// reload reads cookie file again. It returns false on error.
+func (a *cookieAuth) reload() bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.load()
+	return a.err == nil
+}
+
+// auth returns current cookie.
+func (a *cookieAuth) auth() string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.cookie
+}
+
+// setAuth sets Authorization header in req using cookie, reading cookie
+// file if it wasn't read yet. It returns used cookie.
+func (a *cookieAuth) setAuth(req *http.Request) (string, error) {
+	a.mu.Lock()
+	if !a.loaded || a.err != nil {
+		a.load()
+	}
+	cookie, err := a.cookie, a.err
+	a.mu.Unlock()
+	if err != nil {
+		return "", err
+	}
+	i := strings.Index(cookie, ":")
+	req.SetBasicAuth(cookie[:i], cookie[i+1:])
+	return cookie, nil
+}
@@ -0,0 +1,132 @@
+package jsonrpcf
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+// authServer serves rpc.DefaultServer over HTTP to clients which use
+// Basic auth with user:password from *cookie.
+func authServer(mu *sync.Mutex, cookie *string, requests *int) *httptest.Server {
+	h := HTTPHandler(nil)
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		want := *cookie
+		*requests++
+		mu.Unlock()
+		user, pass, ok := r.BasicAuth()
+		if !ok || user+":"+pass != want {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		h.ServeHTTP(w, r)
+	}))
+}
+
+func TestCookieFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jsonrpc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, ".cookie")
+
+	var mu sync.Mutex
+	cookie, requests := "__cookie__:first", 0
+	ts := authServer(&mu, &cookie, &requests)
+	defer ts.Close()
+	client := NewHTTPClient(ts.URL, WithCookieFile(path))
+	defer client.Close()
+
+	var terr *TransportError
+	if err := client.Call("Svc.Sum", [2]int{3, 5}, nil); !errors.As(err, &terr) {
+		t.Errorf("Call() without cookie file, err = %v", err)
+	}
+
+	if err := ioutil.WriteFile(path, []byte(cookie), 0600); err != nil {
+		t.Fatal(err)
+	}
+	var got int
+	if err := client.Call("Svc.Sum", [2]int{3, 5}, &got); err != nil || got != 8 {
+		t.Errorf("Call() = %v, %v, want 8, nil", got, err)
+	}
+
+	// Node restart.
+	mu.Lock()
+	cookie, requests = "__cookie__:second", 0
+	mu.Unlock()
+	if err := ioutil.WriteFile(path, []byte(cookie+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	got = 0
+	if err := client.Call("Svc.Sum", [2]int{3, 5}, &got); err != nil || got != 8 {
+		t.Errorf("Call() after restart = %v, %v, want 8, nil", got, err)
+	}
+	if requests != 2 {
+		t.Errorf("%d requests after restart, want 2", requests)
+	}
+
+	// Wrong cookie: no retry without changes in file.
+	mu.Lock()
+	cookie, requests = "__cookie__:third", 0
+	mu.Unlock()
+	var herr *HTTPError
+	if err := client.Call("Svc.Sum", [2]int{3, 5}, nil); !errors.As(err, &herr) || herr.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Call() with wrong cookie, err = %v", err)
+	}
+	if requests != 1 {
+		t.Errorf("%d requests with wrong cookie, want 1", requests)
+	}
+}
+
+func TestHTTPClientHeaders(t *testing.T) {
+	h := HTTPHandler(nil)
+	headers := make(chan http.Header, 1)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers <- r.Header
+		h.ServeHTTP(w, r)
+	}))
+	defer ts.Close()
+	client := NewHTTPClient(ts.URL,
+		WithBasicAuth("user", "secret"),
+		WithHeader("X-Api-Key", "key"),
+		WithHeader("Content-Type", "text/plain"),
+	)
+	defer client.Close()
+
+	ctx := ContextWithHeader(context.Background(), "X-Request-Id", "1")
+	ctx = ContextWithHeader(ctx, "Accept", "text/plain")
+	var got int
+	if err := client.CallContext(ctx, "Svc.Sum", [2]int{3, 5}, &got); err != nil || got != 8 {
+		t.Errorf("CallContext() = %v, %v, want 8, nil", got, err)
+	}
+	header := <-headers
+	for key, want := range map[string][]string{
+		"X-Api-Key":    {"key"},
+		"X-Request-Id": {"1"},
+		"Content-Type": {contentType},
+		"Accept":       {contentType},
+	} {
+		if !reflect.DeepEqual(header[key], want) {
+			t.Errorf("header %s = %q, want %q", key, header[key], want)
+		}
+	}
+	if user, pass, _ := (&http.Request{Header: header}).BasicAuth(); user != "user" || pass != "secret" {
+		t.Errorf("BasicAuth() = %q, %q, want user, secret", user, pass)
+	}
+
+	if err := client.Call("Svc.Sum", [2]int{3, 5}, &got); err != nil {
+		t.Errorf("Call(), err = %v", err)
+	}
+	if header := <-headers; header.Get("X-Request-Id") != "" || header.Get("X-Api-Key") != "key" {
+		t.Errorf("headers without ctx = %v", header)
+	}
+}
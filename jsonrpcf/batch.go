@@ -1,12 +1,18 @@
 package jsonrpcf
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"net"
 	"net/rpc"
+	"time"
 )
 
-var jErrRequest = json.RawMessage(`{"id":null,"error":{"code":-32600,"message":"Invalid request"}}`)
+var (
+	jErrRequest  = json.RawMessage(`{"id":null,"error":{"code":-32600,"message":"Invalid request"}}`)
+	jErrRequest2 = json.RawMessage(`{"jsonrpc":"2.0","id":null,"error":{"code":-32600,"message":"Invalid request"}}`)
+)
 
 // JSONRPC1 is an internal RPC service used to process batch requests.
 type JSONRPC1 struct{}
@@ -14,15 +20,21 @@ type JSONRPC1 struct{}
 // BatchArg is a param for internal RPC JSONRPC1.Batch.
 type BatchArg struct {
 	srv  *rpc.Server
+	cfg  *serverConfig
 	reqs []*json.RawMessage
 	Ctx
 }
 
 // Batch is an internal RPC method used to process batch requests.
 func (JSONRPC1) Batch(arg BatchArg, replies *[]*json.RawMessage) (err error) {
+	errRequest := &jErrRequest
+	if arg.cfg.jsonrpc2 {
+		errRequest = &jErrRequest2
+	}
+
 	cli, srv := net.Pipe()
 	defer cli.Close()
-	go arg.srv.ServeCodec(NewServerCodecContext(arg.Context(), srv, arg.srv))
+	go arg.srv.ServeCodec(newServerCodec(arg.Context(), srv, arg.srv, arg.cfg))
 
 	replyc := make(chan *json.RawMessage, len(arg.reqs))
 	donec := make(chan struct{}, 1)
@@ -36,17 +48,17 @@ func (JSONRPC1) Batch(arg BatchArg, replies *[]*json.RawMessage) (err error) {
 			} else {
 				*replies = append(*replies, new(json.RawMessage))
 				if dec.Decode((*replies)[len(*replies)-1]) != nil {
-					(*replies)[len(*replies)-1] = &jErrRequest
+					(*replies)[len(*replies)-1] = errRequest
 				}
 			}
 		}
 		donec <- struct{}{}
 	}()
 
-	var testreq serverRequest
+	testreq := serverRequest{jsonrpc2: arg.cfg.jsonrpc2}
 	for _, req := range arg.reqs {
 		if req == nil || json.Unmarshal(*req, &testreq) != nil {
-			replyc <- &jErrRequest
+			replyc <- errRequest
 		} else {
 			if testreq.ID != nil {
 				replyc <- nil
@@ -61,3 +73,321 @@ func (JSONRPC1) Batch(arg BatchArg, replies *[]*json.RawMessage) (err error) {
 	<-donec
 	return
 }
+
+// batchMethod is a fake rpc service method used by client to send batch
+// request. It's never sent to server.
+const batchMethod = "JSONRPC1.Batch"
+
+// BatchCall represents a single call in a batch request.
+type BatchCall struct {
+	ServiceMethod string      // The name of the service and method to call.
+	Args          interface{} // The argument to the function (*struct).
+	Reply         interface{} // The reply from the function (*struct).
+	Error         error       // After completion, the error status.
+}
+
+// Batch collects calls and notifications to be sent to server using a
+// single JSON-RPC batch request.
+//
+// Batch is not safe for concurrent use and must not be reused after Send.
+type Batch struct {
+	client *Client
+	args   batchArgs
+}
+
+// batchArgs is used by Batch to provide requests and context to
+// WriteRequest and get back IDs of sent requests.
+type batchArgs struct {
+	ctx    context.Context
+	calls  []*BatchCall
+	notify []bool   // notify[i] is true if calls[i] is a notification
+	seq    uint64   // rpc sequence number
+	ids    []uint64 // IDs of sent requests (but not notifications)
+	err    error    // error related to whole batch
+}
+
+// batchResults is used by ReadResponseBody to return replies for batch.
+type batchResults struct {
+	results map[uint64]*clientResponse
+	err     *Error // error not related to any request in batch
+}
+
+// Batch returns a new empty batch request.
+func (c *Client) Batch() *Batch {
+	return &Batch{client: c}
+}
+
+// Call adds request to the batch. Returned BatchCall will be filled with
+// reply or error after Send.
+func (b *Batch) Call(serviceMethod string, args interface{}, reply interface{}) *BatchCall {
+	call := &BatchCall{ServiceMethod: serviceMethod, Args: args, Reply: reply}
+	b.args.calls = append(b.args.calls, call)
+	b.args.notify = append(b.args.notify, false)
+	return call
+}
+
+// Notify adds notification to the batch.
+func (b *Batch) Notify(serviceMethod string, args interface{}) {
+	b.args.calls = append(b.args.calls, &BatchCall{ServiceMethod: serviceMethod, Args: args})
+	b.args.notify = append(b.args.notify, true)
+}
+
+// Send sends batch request and waits for replies. It's SendContext with
+// background context.
+func (b *Batch) Send() error {
+	return b.SendContext(context.Background())
+}
+
+// SendContext sends batch request and waits for replies to all its
+// calls.
+//
+// Reply or error for each call is returned in related BatchCall. Calls
+// without reply from server get an error. If whole batch failed (server
+// replied with single error instead of array, request wasn't sent, ctx is
+// done, etc.) then SendContext returns that error, and it's also set in
+// all calls.
+//
+// Client's timeout is the longest timeout of calls in the batch, or no
+// timeout if some call has no timeout.
+func (b *Batch) SendContext(ctx context.Context) error {
+	var d time.Duration
+	for i, call := range b.args.calls {
+		if b.args.notify[i] {
+			continue
+		}
+		t := b.client.codec.cfg.callTimeout(call.ServiceMethod)
+		if t <= 0 {
+			d = 0
+			break
+		}
+		if t > d {
+			d = t
+		}
+	}
+	err := b.send(ctx, d)
+	if err != nil {
+		for i, call := range b.args.calls {
+			if !b.args.notify[i] {
+				call.Error = err
+			}
+		}
+	}
+	return err
+}
+
+func (b *Batch) send(ctx context.Context, d time.Duration) error {
+	if d > 0 {
+		callCtx, cancel := context.WithTimeout(ctx, d)
+		defer cancel()
+		err := b.send(callCtx, 0)
+		if errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil {
+			return &TimeoutError{Method: batchMethod, Duration: d}
+		}
+		return err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	c, a := b.client, &b.args
+	a.ctx = ctx
+	if len(a.calls) == 0 {
+		return nil
+	}
+	if !a.needReply() {
+		return c.codec.writeBatch(&rpc.Request{ServiceMethod: batchMethod, Seq: seqNotify}, a)
+	}
+
+	var reply batchResults
+	call := c.Go(batchMethod, a, &reply, make(chan *rpc.Call, 1))
+	select {
+	case <-call.Done:
+	case <-ctx.Done():
+		if c.codec.cancel(a.ids...) {
+			return ctx.Err()
+		}
+		<-call.Done // reply has been received already
+		if _, ok := a.err.(*TransportError); ok {
+			return ctx.Err() // request has been aborted
+		}
+	}
+	if a.err != nil {
+		return a.err
+	}
+	if call.Error != nil {
+		return transportError(call.Error)
+	}
+
+	ids := a.ids
+	for i, call := range a.calls {
+		if a.notify[i] {
+			continue
+		}
+		resp := reply.results[ids[0]]
+		ids = ids[1:]
+		switch {
+		case resp == nil && reply.err != nil:
+			call.Error = reply.err
+		case resp == nil:
+			call.Error = NewError(errInternal.Code, "no reply")
+		case resp.Error != nil:
+			call.Error = resp.Error
+		case call.Reply != nil:
+			if err := b.client.codec.cfg.unmarshal(*resp.Result, call.Reply); err != nil {
+				call.Error = NewError(errInternal.Code, err.Error())
+			}
+		}
+	}
+	return nil
+}
+
+// needReply returns true if batch contains at least one call which is not
+// a notification.
+func (a *batchArgs) needReply() bool {
+	for _, notify := range a.notify {
+		if !notify {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *clientCodec) writeBatch(r *rpc.Request, a *batchArgs) error {
+	params := make([]interface{}, len(a.calls))
+	for i, call := range a.calls {
+		param, err := checkParams(call.Args)
+		if err != nil {
+			return err
+		}
+		params[i] = param
+	}
+
+	a.seq = r.Seq
+	a.ids = a.ids[:0]
+	reqs := make([]interface{}, len(a.calls))
+	for i, call := range a.calls {
+		if a.notify[i] {
+			reqs[i] = c.request(call.ServiceMethod, params[i], nil)
+			continue
+		}
+		p := &clientPending{seq: r.Seq, method: call.ServiceMethod, batch: a}
+		id, err := c.register(p, nil)
+		if err != nil {
+			c.forget(a.ids...)
+			return err
+		}
+		a.ids = append(a.ids, id)
+		reqs[i] = c.request(call.ServiceMethod, params[i], p.wireID)
+	}
+	if err := c.write(a.ctx, reqs); err != nil {
+		c.forget(a.ids...)
+		return err
+	}
+	return nil
+}
+
+// readBatchResponse fills r using reply to batch request. If ids of
+// related requests are known then reply will be matched to them.
+func (c *clientCodec) readBatchResponse(r *rpc.Response, raw json.RawMessage, ids []uint64) error {
+	var resps []json.RawMessage
+	var bad *Error
+	if err := json.Unmarshal(raw, &resps); err != nil {
+		bad = NewError(errInternal.Code, err.Error())
+	} else if len(resps) == 0 {
+		bad = NewError(errInternal.Code, "bad response: "+string(raw))
+	}
+	if bad != nil {
+		if len(ids) > 0 {
+			return c.failRequests(r, ids, bad)
+		}
+		return bad
+	}
+
+	r.Error = ""
+	r.Seq = seqNotify // ignore reply if we don't know related rpc call
+	var a *batchArgs
+	reply := &batchResults{results: make(map[uint64]*clientResponse)}
+	for _, raw := range resps {
+		resp := &clientResponse{cfg: c.cfg}
+		if err := json.Unmarshal(raw, resp); err != nil {
+			// Bad element can't be related to any call, so it
+			// becomes an error for calls without reply.
+			if reply.err == nil {
+				reply.err = NewError(errInternal.Code, err.Error())
+			}
+			continue
+		}
+		if resp.ID == nil {
+			if resp.Error != nil {
+				reply.err = resp.Error
+			}
+			continue
+		}
+		c.mutex.Lock()
+		id, ok := c.lookup(resp.ID)
+		var p *clientPending
+		var err error
+		if ok {
+			p = c.pending[id]
+			err = p.err
+		}
+		c.mutex.Unlock()
+		if p == nil || p.batch == nil || a != nil && p.batch != a {
+			continue
+		}
+		if err != nil {
+			// Whole batch request has failed.
+			c.failBatch(r, p.batch, err)
+			return nil
+		}
+		a = p.batch
+		reply.results[id] = resp
+	}
+
+	if a == nil && len(ids) > 0 {
+		// Reply to known requests contains no their IDs.
+		if reply.err == nil {
+			reply.err = NewError(errInternal.Code, "bad response: "+string(raw))
+		}
+		return c.failRequests(r, ids, reply.err)
+	}
+	if a == nil {
+		// Reply contains no known IDs, so it's either reply to
+		// unknown batch or error related to whole batch.
+		if a = c.oldestBatch(); a != nil && reply.err != nil {
+			c.failBatch(r, a, reply.err)
+		}
+		return nil
+	}
+	if !c.forget(a.ids...) {
+		return nil // reply to canceled batch
+	}
+	r.ServiceMethod = batchMethod
+	r.Seq = a.seq
+	c.batchResults = reply
+	return nil
+}
+
+// oldestBatch returns pending batch with smallest sequence number or nil.
+func (c *clientCodec) oldestBatch() *batchArgs {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	var a *batchArgs
+	for _, p := range c.pending {
+		if p.batch != nil && (a == nil || p.seq < a.seq) {
+			a = p.batch
+		}
+	}
+	return a
+}
+
+// failBatch fills r to return err for whole batch a.
+func (c *clientCodec) failBatch(r *rpc.Response, a *batchArgs, err error) {
+	if !c.forget(a.ids...) {
+		return // canceled batch
+	}
+	a.err = err
+	r.ServiceMethod = batchMethod
+	r.Seq = a.seq
+	r.Error = err.Error()
+}
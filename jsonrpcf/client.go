@@ -6,56 +6,315 @@
 package jsonrpcf
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"io"
 	"math"
 	"net"
+	"net/http"
 	"net/rpc"
 	"reflect"
+	"strconv"
 	"sync"
-
-//	"fmt"
+	"time"
 )
 
 const seqNotify = math.MaxUint64
 
+// Dialect is a flavour of JSON-RPC protocol spoken by Client.
+type Dialect int
+
+const (
+	// JSONRPC10Versioned is JSON-RPC 1.0 with extra "jsonrpc":"1.0"
+	// member in requests, as sent by bitcoin-cli and expected by some
+	// bitcoind forks (Zcash, Ravencoin). Replies must contain same
+	// "jsonrpc":"1.0" member (see Profile.MissingVersion) and may omit
+	// "error" member if there is no error. This is the default dialect.
+	JSONRPC10Versioned Dialect = iota
+	// JSONRPC10 is strict JSON-RPC 1.0: requests have no "jsonrpc" member
+	// and replies must contain exactly "id", "result" and "error" members.
+	JSONRPC10
+	// JSONRPC20 is JSON-RPC 2.0: requests and replies must contain
+	// "jsonrpc":"2.0" member, notifications have no "id" member and
+	// replies contain either "result" or "error" member, but not both.
+	JSONRPC20
+)
+
+// String returns dialect name.
+func (d Dialect) String() string {
+	switch d {
+	case JSONRPC10Versioned:
+		return "JSON-RPC 1.0 (versioned)"
+	case JSONRPC10:
+		return "JSON-RPC 1.0"
+	case JSONRPC20:
+		return "JSON-RPC 2.0"
+	default:
+		return "Dialect(" + strconv.Itoa(int(d)) + ")"
+	}
+}
+
+// version returns value for "jsonrpc" member or "" if it must be omitted.
+func (d Dialect) version() string {
+	switch d {
+	case JSONRPC10Versioned:
+		return "1.0"
+	case JSONRPC20:
+		return "2.0"
+	default:
+		return ""
+	}
+}
+
+// ClientOption configures clients created by NewClient, Dial,
+// NewHTTPClient and NewCustomHTTPClient.
+type ClientOption func(*clientConfig)
+
+type clientConfig struct {
+	dialect         Dialect
+	profile         *Profile
+	timeout         time.Duration
+	methodTimeouts  map[string]time.Duration
+	retry           *RetryPolicy
+	header          http.Header     // HTTP only
+	basicAuth       *[2]string      // HTTP only: user and password
+	cookie          *cookieAuth     // HTTP only
+	maxInFlight     int             // HTTP only
+	maxQueued       int             // HTTP only
+	getMethods      map[string]bool // HTTP only
+	maxResponseSize int64
+	useNumber       bool
+	newID           IDGenerator
+}
+
+// callTimeout returns timeout for calls to method or 0 if there is none.
+func (cfg *clientConfig) callTimeout(method string) time.Duration {
+	if d, ok := cfg.methodTimeouts[method]; ok {
+		return d
+	}
+	return cfg.timeout
+}
+
+// unmarshal decodes JSON-encoded data into v according to WithUseNumber.
+func (cfg *clientConfig) unmarshal(data []byte, v interface{}) error {
+	if cfg == nil || !cfg.useNumber {
+		return json.Unmarshal(data, v)
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	return dec.Decode(v)
+}
+
+func newClientConfig(opts []ClientOption) *clientConfig {
+	cfg := &clientConfig{
+		dialect: ProfileBitcoinCore.Dialect,
+		profile: ProfileBitcoinCore,
+		newID:   SequenceIDs,
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return cfg
+}
+
+// WithTimeout sets default timeout for calls made using client.Call() and
+// client.CallContext(). Timeout 0 means no timeout (default).
+func WithTimeout(d time.Duration) ClientOption {
+	return func(cfg *clientConfig) {
+		cfg.timeout = d
+	}
+}
+
+// WithMethodTimeout sets timeout for calls to given method, overriding
+// default timeout set by WithTimeout. Timeout 0 means no timeout.
+func WithMethodTimeout(method string, d time.Duration) ClientOption {
+	return func(cfg *clientConfig) {
+		if cfg.methodTimeouts == nil {
+			cfg.methodTimeouts = make(map[string]time.Duration)
+		}
+		cfg.methodTimeouts[method] = d
+	}
+}
+
+// WithUseNumber makes client decode numbers in results and error data
+// into interface{} as json.Number instead of float64, so large integers
+// don't lose precision. See also Amount.
+func WithUseNumber() ClientOption {
+	return func(cfg *clientConfig) {
+		cfg.useNumber = true
+	}
+}
+
+// WithDialect makes client speak given dialect of JSON-RPC protocol
+// (JSONRPC10Versioned by default).
+func WithDialect(d Dialect) ClientOption {
+	return func(cfg *clientConfig) {
+		cfg.dialect = d
+	}
+}
+
 type clientCodec struct {
-	dec *json.Decoder // for reading JSON values
-	enc *json.Encoder // for writing JSON values
-	c   io.Closer
+	dec      *json.Decoder // for reading JSON values
+	jr       *jsonReader   // for reading JSON values if size is limited
+	w        io.Writer     // for writing JSON values
+	c        io.Closer
+	cfg      *clientConfig
+	replies  chan *clientReply // replies read from c
+	once     sync.Once
+	done     chan struct{} // closed by Close
+	loseOnce sync.Once
+	lost     chan struct{} // closed when connection is lost or closed
 
 	// temporary work space
-	resp clientResponse
+	resp         clientResponse
+	batchResults *batchResults
+	discard      bool      // reply is related to canceled call
+	respCall     *callArgs // call related to reply, if any
 
 	// JSON-RPC responses include the request id but not the request method.
 	// Package rpc expects both.
 	// We save the request method in pending when sending a request
 	// and then look it up by request ID when filling out the rpc Response.
-	mutex   sync.Mutex        // protects pending
-	pending map[uint64]string // map request id to method name
+	//
+	// Request ID isn't same as rpc sequence number because batch
+	// request needs many request IDs for a single rpc call. Request ID
+	// also isn't same as ID sent to server (wire ID), which is made by
+	// IDGenerator and may be a string.
+	mutex    sync.Mutex // protects nextID, pending, wireIDs, canceled
+	nextID   uint64
+	pending  map[uint64]*clientPending // map request id to rpc call details
+	wireIDs  map[string]uint64         // map canonical wire ID to request id
+	canceled []uint64                  // rpc sequence numbers of canceled calls
+	signal   chan struct{}             // has value when canceled was added
 }
 
-// newClientCodec returns a new rpc.ClientCodec using JSON-RPC 2.0 on conn.
-func newClientCodec(conn io.ReadWriteCloser) rpc.ClientCodec {
-	return &clientCodec{
+// clientReply is a reply read from connection.
+type clientReply struct {
+	raw      json.RawMessage
+	ids      []uint64 // ids of related requests, if known
+	id       []byte   // "id" of too large reply
+	tooLarge bool
+	err      error
+}
+
+// clientPending describes request waiting for reply.
+type clientPending struct {
+	seq     uint64          // rpc sequence number
+	method  string          // rpc service method
+	call    *callArgs       // call made by Client.CallContext, if any
+	batch   *batchArgs      // batch which contains this request, if any
+	err     error           // transport error, if request has failed
+	wireID  json.RawMessage // ID sent to server
+	wireKey string          // canonical wire ID, see canonicalID
+}
+
+// newClientCodec returns a new rpc.ClientCodec using cfg.dialect on conn.
+func newClientCodec(conn io.ReadWriteCloser, cfg *clientConfig) *clientCodec {
+	c := &clientCodec{
 		dec:     json.NewDecoder(conn),
-		enc:     json.NewEncoder(conn),
+		w:       conn,
 		c:       conn,
-		pending: make(map[uint64]string),
+		cfg:     cfg,
+		replies: make(chan *clientReply),
+		done:    make(chan struct{}),
+		lost:    make(chan struct{}),
+		resp:    clientResponse{cfg: cfg},
+		pending: make(map[uint64]*clientPending),
+		wireIDs: make(map[string]uint64),
+		signal:  make(chan struct{}, 1),
+	}
+	if _, ok := conn.(messageReader); !ok && cfg.maxResponseSize > 0 {
+		c.jr = newJSONReader(conn, cfg.maxResponseSize)
+	}
+	go c.readLoop()
+	return c
+}
+
+// readLoop reads replies from connection until error, so ReadResponseHeader
+// is able to wait for either reply or canceled call.
+func (c *clientCodec) readLoop() {
+	for {
+		reply := &clientReply{}
+		if m, ok := c.c.(messageReader); ok {
+			reply.raw, reply.ids, reply.err = m.readMessage()
+		} else if c.jr != nil {
+			reply.raw, reply.id, reply.tooLarge, reply.err = c.jr.next()
+		} else {
+			reply.err = c.dec.Decode(&reply.raw)
+		}
+		if reply.err != nil {
+			c.lose()
+		}
+		select {
+		case c.replies <- reply:
+		case <-c.done:
+			return
+		}
+		if reply.err != nil {
+			return
+		}
+	}
+}
+
+// lose marks connection as lost.
+func (c *clientCodec) lose() {
+	c.loseOnce.Do(func() { close(c.lost) })
+}
+
+// isLost returns true if connection was lost or closed.
+func (c *clientCodec) isLost() bool {
+	select {
+	case <-c.lost:
+		return true
+	default:
+		return false
 	}
 }
 
 type clientRequest struct {
-        JSONRPC string     `json:"jsonrpc"` // FIX for RVN, ZEC
+	Version string          `json:"jsonrpc,omitempty"`
+	Method  string          `json:"method"`
+	Params  interface{}     `json:"params,omitempty"`
+	ID      json.RawMessage `json:"id"`
+}
 
-	Method string      `json:"method"`
-	Params interface{} `json:"params,omitempty"`
-	ID     *uint64     `json:"id"`
+// clientNotification2 is a JSON-RPC 2.0 notification: unlike JSON-RPC 1.0
+// it must not contain "id" member at all.
+type clientNotification2 struct {
+	Version string      `json:"jsonrpc"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params,omitempty"`
 }
 
-func (c *clientCodec) WriteRequest(r *rpc.Request, param interface{}) error {
-	// If return error: it will be returned as is for this call.
+// contextWriter is implemented by connections able to abort writing
+// request (and waiting for reply) when ctx is done.
+type contextWriter interface {
+	WriteContext(ctx context.Context, buf []byte) (int, error)
+}
+
+// messageReader is implemented by connections which receive each reply
+// as separate message together with ids of requests it replies to (like
+// HTTP), so reply can be matched to requests even without valid id.
+type messageReader interface {
+	readMessage() (msg []byte, ids []uint64, err error)
+}
+
+// callArgs is used by Client.CallContext to provide context to
+// WriteRequest and get back ID of sent request and error returned by
+// server (net/rpc is able to return only error's text).
+type callArgs struct {
+	ctx  context.Context
+	args interface{}
+	id   uint64
+	sent bool
+	err  error
+}
+
+// checkParams returns param to be sent as "params" or error if param has
+// unsupported type.
+func checkParams(param interface{}) (interface{}, error) {
 	// Allow param to be only Array, Slice, Map or Struct.
 	// When param is nil or uninitialized Map or Slice - omit "params".
 	if param != nil {
@@ -85,70 +344,304 @@ func (c *clientCodec) WriteRequest(r *rpc.Request, param interface{}) error {
 				}
 			case reflect.Array, reflect.Struct:
 			default:
-				return NewError(errInternal.Code, "unsupported param type: Ptr to "+k.String())
+				return nil, NewError(errInternal.Code, "unsupported param type: Ptr to "+k.String())
 			}
 		default:
-			return NewError(errInternal.Code, "unsupported param type: "+k.String())
+			return nil, NewError(errInternal.Code, "unsupported param type: "+k.String())
+		}
+	}
+	return param, nil
+}
+
+// request returns request (or notification, if id is nil) in cfg.dialect.
+func (c *clientCodec) request(method string, param interface{}, id json.RawMessage) interface{} {
+	if id == nil && c.cfg.dialect == JSONRPC20 {
+		return &clientNotification2{Version: c.cfg.dialect.version(), Method: method, Params: param}
+	}
+	return &clientRequest{Version: c.cfg.dialect.version(), Method: method, Params: param, ID: id}
+}
+
+// register allocates ID for new request and saves it in pending. Wire ID
+// will be made by cfg.newID if wireID is nil. It returns error if wire ID
+// is not a string or a number or is used by another pending request.
+func (c *clientCodec) register(p *clientPending, wireID interface{}) (uint64, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	id := c.nextID
+	if wireID == nil {
+		wireID = c.cfg.newID(id)
+	}
+	raw, err := json.Marshal(wireID)
+	if err != nil {
+		return 0, NewError(errInternal.Code, "bad request id: "+err.Error())
+	}
+	key, ok := canonicalID(raw)
+	if !ok {
+		return 0, NewError(errInternal.Code, "bad request id: "+string(raw))
+	}
+	if _, ok := c.wireIDs[key]; ok {
+		return 0, NewError(errInternal.Code, "duplicate request id: "+string(raw))
+	}
+	c.nextID++
+	p.wireID, p.wireKey = raw, key
+	c.pending[id] = p
+	c.wireIDs[key] = id
+	return id, nil
+}
+
+// cancel removes pending requests with given IDs (either single call or
+// all calls of single batch), so their replies will be ignored. Related
+// call will be removed from rpc.Client by ReadResponseHeader. It returns
+// false if requests are not pending, i.e. reply is being delivered
+// already.
+func (c *clientCodec) cancel(ids ...uint64) bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if len(ids) == 0 || c.pending[ids[0]] == nil {
+		return false
+	}
+	p := c.pending[ids[0]]
+	c.remove(ids)
+	c.canceled = append(c.canceled, p.seq)
+	select {
+	case c.signal <- struct{}{}:
+	default:
+	}
+	return true
+}
+
+// forget removes pending requests with given IDs. It returns false if
+// they are not pending (i.e. were canceled), so reply must be ignored.
+func (c *clientCodec) forget(ids ...uint64) bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.remove(ids)
+}
+
+// remove removes pending requests with given IDs and returns true if any
+// of them was pending. It must be called with c.mutex held.
+func (c *clientCodec) remove(ids []uint64) bool {
+	removed := false
+	for _, id := range ids {
+		if p := c.pending[id]; p != nil {
+			delete(c.wireIDs, p.wireKey)
+			delete(c.pending, id)
+			removed = true
 		}
 	}
+	return removed
+}
+
+// nextCanceled returns rpc sequence number of canceled call which wasn't
+// removed from rpc.Client yet.
+func (c *clientCodec) nextCanceled() (uint64, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if len(c.canceled) == 0 {
+		return 0, false
+	}
+	seq := c.canceled[0]
+	c.canceled = c.canceled[1:]
+	return seq, true
+}
+
+// lookup returns id of pending request with given wire ID. It must be
+// called with c.mutex held.
+func (c *clientCodec) lookup(wireID *json.RawMessage) (uint64, bool) {
+	if wireID == nil {
+		return 0, false
+	}
+	key, ok := canonicalID(*wireID)
+	if !ok {
+		return 0, false
+	}
+	id, ok := c.wireIDs[key]
+	return id, ok
+}
 
-	var req clientRequest
+// idsOf returns ids of pending requests with given wire IDs.
+func (c *clientCodec) idsOf(wireIDs []json.RawMessage) []uint64 {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	var ids []uint64
+	for i := range wireIDs {
+		if id, ok := c.lookup(&wireIDs[i]); ok {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
 
-	req.JSONRPC = "1.0" // FIX for ZEC, RVN
+// requestIDs returns wire IDs of requests (but not notifications) in
+// JSON-encoded request or batch request buf.
+func requestIDs(buf []byte) []json.RawMessage {
+	type request struct {
+		ID *json.RawMessage `json:"id"`
+	}
+	var reqs []request
+	if len(buf) > 0 && buf[0] == '[' {
+		json.Unmarshal(buf, &reqs)
+	} else {
+		var req request
+		json.Unmarshal(buf, &req)
+		reqs = append(reqs, req)
+	}
+	var ids []json.RawMessage
+	for _, req := range reqs {
+		if req.ID != nil {
+			ids = append(ids, *req.ID)
+		}
+	}
+	return ids
+}
 
-	if r.Seq != seqNotify {
+// failed marks requests (but not notifications) in JSON-encoded request or
+// batch request buf as failed because of transport error err. It returns
+// replies with err for these requests, encoded in a way acceptable by
+// clientResponse in cfg.dialect, or nil if there is nothing to reply.
+func (c *clientCodec) failed(buf []byte, err error) []byte {
+	batch := len(buf) > 0 && buf[0] == '['
+	ids := requestIDs(buf)
+	var resps []clientResponse
+	for i := range ids {
+		id := &ids[i]
 		c.mutex.Lock()
-		c.pending[r.Seq] = r.ServiceMethod
+		if n, ok := c.lookup(id); ok {
+			c.pending[n].err = &TransportError{Err: err}
+		}
 		c.mutex.Unlock()
-		req.ID = &r.Seq
+		resp := clientResponse{Version: c.cfg.dialect.version(), ID: id, Error: NewError(errInternal.Code, err.Error())}
+		if c.cfg.dialect != JSONRPC20 {
+			resp.Result = &null
+		}
+		resps = append(resps, resp)
+	}
+	if len(resps) == 0 {
+		return nil
 	}
-	req.Method = r.ServiceMethod
-	req.Params = param
-	if err := c.enc.Encode(&req); err != nil {
+	var reply interface{} = resps
+	if !batch {
+		reply = resps[0]
+	}
+	out, _ := json.Marshal(reply) // can't fail: Error.Data is not used here
+	return append(out, '\n')
+}
+
+// write sends JSON-encoded v using ctx if conn support it.
+func (c *clientCodec) write(ctx context.Context, v interface{}) error {
+	buf, err := json.Marshal(v)
+	if err != nil {
 		return NewError(errInternal.Code, err.Error())
 	}
+	buf = append(buf, '\n')
+	if w, ok := c.w.(contextWriter); ok {
+		_, err = w.WriteContext(ctx, buf)
+	} else if _, err = c.w.Write(buf); err != nil {
+		c.lose() // stream is broken after failed write
+	}
+	if err != nil {
+		return &TransportError{Err: err}
+	}
+	return nil
+}
+
+func (c *clientCodec) WriteRequest(r *rpc.Request, param interface{}) error {
+	// If return error: it will be returned as is for this call.
+	if b, ok := param.(*batchArgs); ok {
+		return c.writeBatch(r, b)
+	}
+	ctx := context.Background()
+	a, _ := param.(*callArgs)
+	if a != nil {
+		ctx, param = a.ctx, a.args
+	}
+	param, err := checkParams(param)
+	if err != nil {
+		return err
+	}
+
+	if r.Seq == seqNotify {
+		return c.write(ctx, c.request(r.ServiceMethod, param, nil))
+	}
+	p := &clientPending{seq: r.Seq, method: r.ServiceMethod, call: a}
+	id, err := c.register(p, ctx.Value(requestIDContextKey))
+	if err != nil {
+		return err
+	}
+	if a != nil {
+		a.id, a.sent = id, true
+	}
+	if err := c.write(ctx, c.request(r.ServiceMethod, param, p.wireID)); err != nil {
+		c.forget(id)
+		return err
+	}
 	return nil
 }
 
 type clientResponse struct {
-	ID     *uint64          `json:"id"`
-	Result *json.RawMessage `json:"result,omitempty"`
-	Error  *Error           `json:"error"`
+	Version string           `json:"jsonrpc,omitempty"`
+	ID      *json.RawMessage `json:"id"`
+	Result  *json.RawMessage `json:"result,omitempty"`
+	Error   *Error           `json:"error"`
+
+	cfg *clientConfig // dialect and profile used to validate response
 }
 
 func (r *clientResponse) reset() {
+	r.Version = ""
 	r.ID = nil
 	r.Result = nil
 	r.Error = nil
 }
 
 func (r *clientResponse) UnmarshalJSON(raw []byte) error {
-	r.reset()	
-
+	r.reset()
 	type resp *clientResponse
-	if err := json.Unmarshal(raw, resp(r)); err != nil {
-		return errors.New("bad response (1): " + string(raw))
+	if err := r.cfg.unmarshal(raw, resp(r)); err != nil {
+		return errors.New("bad response: " + string(raw))
 	}
 
 	var o = make(map[string]*json.RawMessage)
 	if err := json.Unmarshal(raw, &o); err != nil {
-		return errors.New("bad response (2): " + string(raw))
+		return errors.New("bad response: " + string(raw))
 	}
-
-	//!!!FIX!!! Workaround for invalid Zcash's JSONRPC implementation: they return messages like {"result":null,"error":{"code":-5,"message":"error message"},"id":0}
-        //where both fields "result" and "error" exists
-
+	for k := range o {
+		switch k {
+		case "jsonrpc", "id", "result", "error":
+		default:
+			return errors.New("bad response: " + string(raw))
+		}
+	}
+	ver, okVer := o["jsonrpc"]
 	_, okID := o["id"]
 	res, okRes := o["result"]
-	//err, okErr := o["error"]
-
-	err, _ := o["error"]
+	err, okErr := o["error"]
 
-	//fmt.Printf("DEBUG (1): %t %t %t %t %t\n", !okID, !okErr, !(okRes || (err != nil)), (okRes && (res != nil) && (err != nil)), len(o) > 3)
-
-	//if !okID || !okErr || !(okRes || (err != nil)) || (okRes && (res != nil) && (err != nil)) || len(o) > 3 {
-	if !okID || !(okRes || (err != nil)) || (okRes && (res != nil) && (err != nil)) || len(o) > 3 {
-		return errors.New("bad response (3): " + string(raw))
+	quirks := r.cfg.profile
+	switch r.cfg.dialect {
+	case JSONRPC10:
+		if okVer || !okRes || !okErr {
+			return errors.New("bad response: " + string(raw))
+		}
+	case JSONRPC10Versioned:
+		if okVer && r.Version != "1.0" || !okVer && !quirks.MissingVersion || !okRes && err == nil {
+			return errors.New("bad response: " + string(raw))
+		}
+	case JSONRPC20:
+		if okVer && (ver == nil || r.Version != "2.0") || !okVer && !quirks.MissingVersion {
+			return errors.New("bad response: " + string(raw))
+		}
+		if okRes && okErr && !quirks.ResultWithError || !okRes && err == nil {
+			return errors.New("bad response: " + string(raw))
+		}
+	}
+	if !okID || res != nil && err != nil {
+		return errors.New("bad response: " + string(raw))
+	}
+	if id := o["id"]; id != nil {
+		if _, ok := canonicalID(*id); !ok {
+			return errors.New("bad response: " + string(raw))
+		}
 	}
 
 	if okRes && r.Result == nil {
@@ -158,18 +651,18 @@ func (r *clientResponse) UnmarshalJSON(raw []byte) error {
 	if err != nil {
 		oe := make(map[string]*json.RawMessage)
 		if errRPC := json.Unmarshal(*err, &oe); errRPC != nil {
-			return errors.New("bad response (4): " + string(raw))
+			return errors.New("bad response: " + string(raw))
 		}
 		if oe["code"] == nil || oe["message"] == nil {
-			return errors.New("bad response (5): " + string(raw))
+			return errors.New("bad response: " + string(raw))
 		}
 		if _, ok := oe["data"]; (!ok && len(oe) > 2) || len(oe) > 3 {
-			return errors.New("bad response (6): " + string(raw))
+			return errors.New("bad response: " + string(raw))
 		}
 	}
 
 	if o["id"] == nil && err == nil {
-		return errors.New("bad response (7): " + string(raw))
+		return errors.New("bad response: " + string(raw))
 	}
 
 	return nil
@@ -180,30 +673,156 @@ func (c *clientCodec) ReadResponseHeader(r *rpc.Response) error {
 	// - io.EOF will became ErrShutdown or io.ErrUnexpectedEOF
 	// - it will be returned as is for all pending calls
 	// - client will be shutdown
-	// So, return io.EOF as is, return *Error for all other errors.
-	if err := c.dec.Decode(&c.resp); err != nil {
+	// So, return io.EOF as is, return *TransportError for other read
+	// errors and *Error for all other errors.
+	c.discard, c.respCall = false, nil
+	var reply *clientReply
+	for reply == nil {
+		if seq, ok := c.nextCanceled(); ok {
+			// Reply to canceled call will be ignored, but call
+			// must be removed from rpc.Client.
+			r.ServiceMethod, r.Seq, r.Error = "", seq, ""
+			c.discard = true
+			return nil
+		}
+		select {
+		case reply = <-c.replies:
+		case <-c.signal:
+		}
+	}
+	raw := reply.raw
+	var ids []uint64 // ids of related requests, if known
+	if _, ok := c.c.(messageReader); ok {
+		if reply.err != nil {
+			return reply.err
+		}
+		if len(reply.ids) == 0 {
+			r.Error = ""
+			r.Seq = seqNotify // reply to notification
+			return nil
+		}
+		raw, ids = bytes.TrimSpace(raw), reply.ids
+		if !json.Valid(raw) {
+			return c.failRequests(r, ids, NewError(errInternal.Code, "bad response: "+string(raw)))
+		}
+	} else if c.jr != nil {
+		switch {
+		case reply.err == io.EOF:
+			return reply.err
+		case reply.err != nil:
+			return &TransportError{Err: reply.err}
+		case reply.tooLarge:
+			return c.tooLarge(r, reply.id)
+		case !json.Valid(raw):
+			return NewError(errInternal.Code, "bad response: "+string(raw))
+		}
+	} else if err := reply.err; err != nil {
 		if err == io.EOF {
 			return err
 		}
-		return NewError(errInternal.Code, err.Error())
+		if _, ok := err.(*json.SyntaxError); ok {
+			return NewError(errInternal.Code, err.Error())
+		}
+		return &TransportError{Err: err}
 	}
-	if c.resp.ID == nil {
-		return c.resp.Error
+	if len(raw) > 0 && raw[0] == '[' {
+		return c.readBatchResponse(r, raw, ids)
+	}
+	if err := json.Unmarshal(raw, &c.resp); err != nil {
+		if len(ids) > 0 {
+			return c.failRequests(r, ids, NewError(errInternal.Code, err.Error()))
+		}
+		return NewError(errInternal.Code, err.Error())
 	}
 
+	r.Error = ""
+	r.Seq = seqNotify // ignore reply if we don't know related rpc call
 	c.mutex.Lock()
-	r.ServiceMethod = c.pending[*c.resp.ID]
-	delete(c.pending, *c.resp.ID)
+	id, known := c.lookup(c.resp.ID)
+	var p *clientPending
+	if known {
+		p = c.pending[id]
+	}
 	c.mutex.Unlock()
+	if len(ids) > 0 && (!known || !hasID(ids, id)) {
+		// Reply without id or with wrong id to known requests.
+		err := c.resp.Error
+		if err == nil {
+			err = NewError(errInternal.Code, "bad response: "+string(raw))
+		}
+		return c.failRequests(r, ids, err)
+	}
+	if c.resp.ID == nil {
+		// Some servers reply to bad batch with single error.
+		b := c.oldestBatch()
+		if b == nil || c.resp.Error == nil {
+			return c.resp.Error
+		}
+		c.failBatch(r, b, c.resp.Error)
+		return nil
+	}
 
-	r.Error = ""
-	r.Seq = *c.resp.ID
+	if p != nil && p.batch != nil {
+		// Some servers reply to batch with single element using object.
+		return c.readBatchResponse(r, append(append(json.RawMessage{'['}, raw...), ']'), ids)
+	}
+	if p != nil && !c.forget(id) {
+		p = nil // reply to canceled call
+	}
+	if p != nil {
+		c.respCall = p.call
+		r.ServiceMethod = p.method
+		r.Seq = p.seq
+	}
 	if c.resp.Error != nil {
 		r.Error = c.resp.Error.Error()
+		if p != nil && p.call != nil {
+			c.mutex.Lock()
+			err := p.err
+			c.mutex.Unlock()
+			if err == nil {
+				err = c.resp.Error
+			}
+			p.call.err = err
+		}
 	}
 	return nil
 }
 
+// failRequests fills r to return err for requests with given ids (either
+// single call or all calls of single batch).
+func (c *clientCodec) failRequests(r *rpc.Response, ids []uint64, err error) error {
+	r.Error = ""
+	r.Seq = seqNotify
+	c.mutex.Lock()
+	p := c.pending[ids[0]]
+	c.mutex.Unlock()
+	switch {
+	case p == nil: // reply to unknown request
+	case p.batch != nil:
+		c.failBatch(r, p.batch, err)
+	case !c.forget(ids[0]): // reply to canceled call
+	default:
+		r.ServiceMethod = p.method
+		r.Seq = p.seq
+		r.Error = err.Error()
+		if p.call != nil {
+			p.call.err = err
+		}
+	}
+	return nil
+}
+
+// hasID returns true if ids contains id.
+func hasID(ids []uint64, id uint64) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
+
 func (c *clientCodec) ReadResponseBody(x interface{}) error {
 	// If x!=nil and return error e:
 	// - this call get e.Error() appended to "reading body "
@@ -213,7 +832,18 @@ func (c *clientCodec) ReadResponseBody(x interface{}) error {
 	if x == nil {
 		return nil
 	}
-	if err := json.Unmarshal(*c.resp.Result, x); err != nil {
+	if c.discard {
+		c.batchResults = nil
+		return nil
+	}
+	if reply, ok := x.(*batchResults); ok {
+		*reply, c.batchResults = *c.batchResults, nil
+		return nil
+	}
+	if err := c.cfg.unmarshal(*c.resp.Result, x); err != nil {
+		if c.respCall != nil {
+			c.respCall.err = NewError(errInternal.Code, err.Error())
+		}
 		e := NewError(errInternal.Code, err.Error())
 		e.Data = NewError(errInternal.Code, "some other Call failed to unmarshal Reply")
 		return e
@@ -222,6 +852,8 @@ func (c *clientCodec) ReadResponseBody(x interface{}) error {
 }
 
 func (c *clientCodec) Close() error {
+	c.once.Do(func() { close(c.done) })
+	c.lose()
 	return c.c.Close()
 }
 
@@ -236,6 +868,75 @@ type Client struct {
 	codec *clientCodec
 }
 
+// Call invokes the named function, waits for it to complete, and returns
+// its error status. It's CallContext with background context.
+//
+// Error returned by server is returned as *Error, transport failures
+// (including rpc.ErrShutdown and io.ErrUnexpectedEOF) are returned as
+// *TransportError.
+func (c *Client) Call(serviceMethod string, args interface{}, reply interface{}) error {
+	return c.CallContext(context.Background(), serviceMethod, args, reply)
+}
+
+// CallContext invokes the named function, waits for it to complete, and
+// returns its error status.
+//
+// If ctx is done before reply was received then CallContext returns
+// ctx.Err() and reply will be left untouched: late reply will be
+// ignored and HTTP request (when using HTTP client) will be aborted.
+//
+// If client's timeout (see WithTimeout and WithMethodTimeout) expires
+// before reply was received then CallContext returns *TimeoutError.
+//
+// Failed call will be retried according to client's RetryPolicy (see
+// WithRetry).
+func (c *Client) CallContext(ctx context.Context, serviceMethod string, args interface{}, reply interface{}) error {
+	if p := c.codec.cfg.retry; p.allowed(serviceMethod) {
+		return p.do(ctx, func() error {
+			return c.attempt(ctx, serviceMethod, args, reply)
+		})
+	}
+	return c.attempt(ctx, serviceMethod, args, reply)
+}
+
+// attempt makes a call using client's timeout.
+func (c *Client) attempt(ctx context.Context, serviceMethod string, args interface{}, reply interface{}) error {
+	d := c.codec.cfg.callTimeout(serviceMethod)
+	if d <= 0 {
+		return c.call(ctx, serviceMethod, args, reply)
+	}
+	callCtx, cancel := context.WithTimeout(ctx, d)
+	defer cancel()
+	err := c.call(callCtx, serviceMethod, args, reply)
+	if err == context.DeadlineExceeded && ctx.Err() == nil {
+		return &TimeoutError{Method: serviceMethod, Duration: d}
+	}
+	return err
+}
+
+func (c *Client) call(ctx context.Context, serviceMethod string, args interface{}, reply interface{}) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	a := &callArgs{ctx: ctx, args: args}
+	call := c.Go(serviceMethod, a, reply, make(chan *rpc.Call, 1))
+	select {
+	case <-call.Done:
+	case <-ctx.Done():
+		if !a.sent || c.codec.cancel(a.id) {
+			return ctx.Err()
+		}
+		<-call.Done // reply has been received already
+		if _, ok := a.err.(*TransportError); ok {
+			return ctx.Err() // request has been aborted
+		}
+	}
+	if a.err != nil {
+		return a.err
+	}
+	return transportError(call.Error)
+}
+
 // Notify try to invoke the named function. It return error only in case
 // it wasn't able to send request.
 func (c Client) Notify(serviceMethod string, args interface{}) error {
@@ -248,17 +949,21 @@ func (c Client) Notify(serviceMethod string, args interface{}) error {
 
 // NewClient returns a new Client to handle requests to the
 // set of services at the other end of the connection.
-func NewClient(conn io.ReadWriteCloser) *Client {
-	codec := newClientCodec(conn)
+func NewClient(conn io.ReadWriteCloser, opts ...ClientOption) *Client {
+	return newClient(conn, newClientConfig(opts))
+}
+
+func newClient(conn io.ReadWriteCloser, cfg *clientConfig) *Client {
+	codec := newClientCodec(conn, cfg)
 	client := rpc.NewClientWithCodec(codec)
-	return &Client{client, codec.(*clientCodec)}
+	return &Client{client, codec}
 }
 
-// Dial connects to a JSON-RPC 2.0 server at the specified network address.
-func Dial(network, address string) (*Client, error) {
+// Dial connects to a JSON-RPC server at the specified network address.
+func Dial(network, address string, opts ...ClientOption) (*Client, error) {
 	conn, err := net.Dial(network, address)
 	if err != nil {
 		return nil, err
 	}
-	return NewClient(conn), err
+	return NewClient(conn, opts...), err
 }
@@ -0,0 +1,815 @@
+package jsonrpcf
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/rpc"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCallContext(t *testing.T) {
+	cli, srv := net.Pipe()
+	go ServeConn(srv)
+	client := NewClient(cli)
+	defer client.Close()
+
+	var got int
+	err := client.CallContext(context.Background(), "Svc.Sum", [2]int{3, 5}, &got)
+	if err != nil || got != 8 {
+		t.Errorf("CallContext() = %v, %v, want 8, nil", got, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := client.CallContext(ctx, "Svc.Sum", [2]int{3, 5}, &got); err != context.Canceled {
+		t.Errorf("CallContext(canceled), err = %v", err)
+	}
+}
+
+func TestCallContextCancel(t *testing.T) {
+	cli, srv := net.Pipe()
+	defer srv.Close()
+	client := NewClient(cli)
+	defer client.Close()
+	buf := bufio.NewReader(srv)
+
+	release := make(chan struct{})
+	go func() {
+		buf.ReadString('\n')
+		<-release
+		srv.Write([]byte(`{"id":0,"result":8,"error":null}` + "\n"))
+		buf.ReadString('\n')
+		srv.Write([]byte(`{"id":1,"result":15,"error":null}` + "\n"))
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	got := 42
+	err := client.CallContext(ctx, "Svc.Sum", [2]int{3, 5}, &got)
+	if err != context.DeadlineExceeded {
+		t.Errorf("CallContext(), err = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if pending := codecPending(client); pending != 0 {
+		t.Errorf("len(pending) = %d, want 0", pending)
+	}
+
+	// Late reply must be ignored and must not break following calls.
+	close(release)
+	var got2 int
+	err = client.CallContext(context.Background(), "Svc.Sum", [2]int{10, 5}, &got2)
+	if err != nil || got2 != 15 {
+		t.Errorf("CallContext() = %v, %v, want 15, nil", got2, err)
+	}
+	if got != 42 {
+		t.Errorf("reply of canceled call = %d, want untouched 42", got)
+	}
+	if pending := codecPending(client); pending != 0 {
+		t.Errorf("len(pending) = %d, want 0", pending)
+	}
+	if pending := rpcPending(client); pending != 0 {
+		t.Errorf("len(rpc.Client.pending) = %d, want 0", pending)
+	}
+}
+
+// codecPending returns amount of requests waiting for reply in client's
+// codec.
+func codecPending(client *Client) int {
+	client.codec.mutex.Lock()
+	defer client.codec.mutex.Unlock()
+	return len(client.codec.pending)
+}
+
+// rpcPending returns amount of calls waiting for reply in rpc.Client.
+// Caller must ensure there are no calls in progress.
+func rpcPending(client *Client) int {
+	return reflect.ValueOf(client.Client).Elem().FieldByName("pending").Len()
+}
+
+func TestCallContextHTTP(t *testing.T) {
+	aborted := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ioutil.ReadAll(r.Body) // let server notice closed connection
+		<-r.Context().Done()
+		close(aborted)
+	}))
+	defer ts.Close()
+	client := NewHTTPClient(ts.URL)
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err := client.CallContext(ctx, "Svc.Sum", [2]int{3, 5}, nil)
+	if err != context.DeadlineExceeded {
+		t.Errorf("CallContext(), err = %v, want %v", err, context.DeadlineExceeded)
+	}
+	select {
+	case <-aborted:
+	case <-time.After(time.Second):
+		t.Errorf("HTTP request wasn't aborted")
+	}
+}
+
+func TestCallTimeout(t *testing.T) {
+	cli, srv := net.Pipe()
+	defer srv.Close()
+	client := NewClient(cli,
+		WithTimeout(10*time.Millisecond),
+		WithMethodTimeout("Svc.Slow", 50*time.Millisecond),
+		WithMethodTimeout("Svc.Wait", 0),
+	)
+	defer client.Close()
+	go ioutil.ReadAll(srv)
+
+	cases := []struct {
+		method string
+		want   time.Duration
+	}{
+		{"Svc.Sum", 10 * time.Millisecond},
+		{"Svc.Slow", 50 * time.Millisecond},
+	}
+	for _, c := range cases {
+		err := client.Call(c.method, nil, nil)
+		te, ok := err.(*TimeoutError)
+		if !ok || te.Method != c.method || te.Duration != c.want {
+			t.Errorf("Call(%s), err = %#v, want TimeoutError after %v", c.method, err, c.want)
+		}
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Call(%s), err = %v is not context.DeadlineExceeded", c.method, err)
+		}
+	}
+
+	// Caller's context expires before client's timeout.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	if err := client.CallContext(ctx, "Svc.Slow", nil, nil); err != context.DeadlineExceeded {
+		t.Errorf("CallContext(Svc.Slow), err = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	// No timeout for this method.
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := client.CallContext(ctx, "Svc.Wait", nil, nil); err != context.DeadlineExceeded {
+		t.Errorf("CallContext(Svc.Wait), err = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestBatchTimeout(t *testing.T) {
+	cli, srv := net.Pipe()
+	defer srv.Close()
+	client := NewClient(cli,
+		WithTimeout(10*time.Millisecond),
+		WithMethodTimeout("Svc.Slow", 20*time.Millisecond),
+		WithMethodTimeout("Svc.Wait", 0),
+	)
+	defer client.Close()
+	go ioutil.ReadAll(srv)
+
+	b := client.Batch()
+	b.Call("Svc.Sum", nil, nil)
+	b.Call("Svc.Slow", nil, nil)
+	b.Notify("Svc.Wait", nil)
+	err := b.Send()
+	if te, ok := err.(*TimeoutError); !ok || te.Duration != 20*time.Millisecond {
+		t.Errorf("Send(), err = %#v, want TimeoutError after 20ms", err)
+	}
+
+	// Batch has no timeout if some call has no timeout.
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	b = client.Batch()
+	b.Call("Svc.Sum", nil, nil)
+	b.Call("Svc.Wait", nil, nil)
+	if err := b.SendContext(ctx); err != context.DeadlineExceeded {
+		t.Errorf("SendContext(), err = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestCallTimeoutPending(t *testing.T) {
+	cli, srv := net.Pipe()
+	defer srv.Close()
+	client := NewClient(cli, WithMethodTimeout("Svc.Sum", 10*time.Millisecond))
+	defer client.Close()
+	buf := bufio.NewReader(srv)
+
+	release := make(chan struct{})
+	go func() {
+		for i := 0; i < 3; i++ {
+			buf.ReadString('\n')
+		}
+		<-release
+		for i := 0; i < 3; i++ {
+			fmt.Fprintf(srv, `{"id":%d,"result":8,"error":null}`+"\n", i)
+		}
+		buf.ReadString('\n')
+		srv.Write([]byte(`{"id":3,"result":15,"error":null}` + "\n"))
+	}()
+
+	for i := 0; i < 3; i++ {
+		if err := client.Call("Svc.Sum", [2]int{3, 5}, nil); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Call(), err = %v, want timeout", err)
+		}
+	}
+	close(release)
+	var got int
+	if err := client.Call("Svc.SumAll", []int{10, 5}, &got); err != nil || got != 15 {
+		t.Errorf("Call() = %v, %v, want 15, nil", got, err)
+	}
+	if pending := codecPending(client); pending != 0 {
+		t.Errorf("len(pending) = %d, want 0", pending)
+	}
+	if pending := rpcPending(client); pending != 0 {
+		t.Errorf("len(rpc.Client.pending) = %d, want 0", pending)
+	}
+}
+
+func TestCallTimeoutPendingHTTP(t *testing.T) {
+	h := HTTPHandler(nil)
+	release := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+			h.ServeHTTP(w, r)
+		case <-r.Context().Done():
+		}
+	}))
+	defer ts.Close()
+	// Second and third calls will time out while waiting for a slot.
+	client := NewHTTPClient(ts.URL, WithMethodTimeout("Svc.Sum", 10*time.Millisecond), WithMaxInFlight(1, 2))
+	defer client.Close()
+
+	errc := make(chan error, 3)
+	for i := 0; i < 3; i++ {
+		go func() { errc <- client.Call("Svc.Sum", [2]int{3, 5}, nil) }()
+	}
+	for i := 0; i < 3; i++ {
+		if err := <-errc; !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Call(), err = %v, want timeout", err)
+		}
+	}
+	// Failed replies are delivered after calls has returned.
+	for start := time.Now(); codecPending(client) != 0; {
+		if time.Since(start) > time.Second {
+			t.Fatalf("len(pending) = %d, want 0", codecPending(client))
+		}
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	if err := client.Call("Svc.SumAll", []int{3, 5}, nil); err != nil {
+		t.Errorf("Call(), err = %v", err)
+	}
+	if pending := rpcPending(client); pending != 0 {
+		t.Errorf("len(rpc.Client.pending) = %d, want 0", pending)
+	}
+}
+
+func TestClientBatch(t *testing.T) {
+	cli, srv := net.Pipe()
+	go ServeConn(srv)
+	client := NewClient(cli)
+	defer client.Close()
+
+	b := client.Batch()
+	var sum, sumAll int
+	var name NameRes
+	call1 := b.Call("Svc.Sum", [2]int{3, 5}, &sum)
+	call2 := b.Call("Svc.Err2", struct{}{}, nil)
+	b.Notify("Svc.Msg", [1]string{"batch"})
+	call3 := b.Call("Svc.SumAll", []int{1, 2, 3}, &sumAll)
+	call4 := b.Call("Svc.Name", NameArg{"First", "Last"}, &name)
+	if err := b.Send(); err != nil {
+		t.Fatalf("Send(), err = %v", err)
+	}
+	if call1.Error != nil || sum != 8 {
+		t.Errorf("Svc.Sum = %v, %v, want 8, nil", sum, call1.Error)
+	}
+	if err, ok := call2.Error.(*Error); !ok || err.Code != 42 {
+		t.Errorf("Svc.Err2, err = %#v", call2.Error)
+	}
+	if call3.Error != nil || sumAll != 6 {
+		t.Errorf("Svc.SumAll = %v, %v, want 6, nil", sumAll, call3.Error)
+	}
+	if call4.Error != nil || name.Name != "First Last" {
+		t.Errorf("Svc.Name = %v, %v", name, call4.Error)
+	}
+	if msg := <-svcMsg; msg != "batch" {
+		t.Errorf("Svc.Msg got %q, want %q", msg, "batch")
+	}
+
+	var got int
+	if err := client.Call("Svc.Sum", [2]int{1, 2}, &got); err != nil || got != 3 {
+		t.Errorf("Call() after batch = %v, %v, want 3, nil", got, err)
+	}
+}
+
+func TestClientBatchReplies(t *testing.T) {
+	cases := []struct {
+		resp     string
+		wantErr  bool
+		wantSums [2]int
+		wantErrs [2]string
+	}{
+		{
+			`[{"id":1,"result":2,"error":null},{"id":0,"result":1,"error":null}]`,
+			false, [2]int{1, 2}, [2]string{"", ""},
+		},
+		{
+			`[{"id":1,"result":null,"error":{"code":-5,"message":"msg"}}]`,
+			false, [2]int{0, 0}, [2]string{"no reply", "msg"},
+		},
+		{
+			`{"id":0,"result":1,"error":null}`,
+			false, [2]int{1, 0}, [2]string{"", "no reply"},
+		},
+		{
+			`{"id":null,"result":null,"error":{"code":-32600,"message":"Invalid request"}}`,
+			true, [2]int{0, 0}, [2]string{"Invalid request", "Invalid request"},
+		},
+		{
+			`[{"id":null,"result":null,"error":{"code":-32600,"message":"Invalid request"}},{"id":0,"result":1,"error":null}]`,
+			false, [2]int{1, 0}, [2]string{"", "Invalid request"},
+		},
+	}
+	for _, c := range cases {
+		cli, srv := net.Pipe()
+		defer srv.Close()
+		client := NewClient(cli)
+		defer client.Close()
+		buf := bufio.NewReader(srv)
+
+		go func() {
+			req, _ := buf.ReadString('\n')
+			want := `[{"jsonrpc":"1.0","method":"Svc.Sum","params":[0,1],"id":0},{"jsonrpc":"1.0","method":"Svc.Sum","params":[1,1],"id":1}]`
+			if !jsonEqual(req, want) {
+				t.Errorf("request\nexp: %#q\ngot: %#q", want, req)
+			}
+			srv.Write([]byte(c.resp + "\n"))
+		}()
+
+		b := client.Batch()
+		var sums [2]int
+		var calls [2]*BatchCall
+		for i := range calls {
+			calls[i] = b.Call("Svc.Sum", [2]int{i, 1}, &sums[i])
+		}
+		if err := b.Send(); (err != nil) != c.wantErr {
+			t.Errorf("%s: Send(), err = %v", c.resp, err)
+		}
+		if sums != c.wantSums {
+			t.Errorf("%s: sums = %v, want %v", c.resp, sums, c.wantSums)
+		}
+		for i, call := range calls {
+			if err := call.Error; err == nil && c.wantErrs[i] != "" || err != nil && !strings.Contains(err.Error(), c.wantErrs[i]) || c.wantErrs[i] == "" && err != nil {
+				t.Errorf("%s: call %d, err = %v, want %q", c.resp, i, err, c.wantErrs[i])
+			}
+		}
+		client.codec.mutex.Lock()
+		pending := len(client.codec.pending)
+		client.codec.mutex.Unlock()
+		if pending != 0 {
+			t.Errorf("%s: pending = %d, want 0", c.resp, pending)
+		}
+	}
+}
+
+func TestClientBatchCancel(t *testing.T) {
+	cli, srv := net.Pipe()
+	defer srv.Close()
+	client := NewClient(cli, WithMethodTimeout("Svc.Sum", 10*time.Millisecond))
+	defer client.Close()
+	buf := bufio.NewReader(srv)
+
+	release := make(chan struct{})
+	go func() {
+		buf.ReadString('\n')
+		buf.ReadString('\n')
+		<-release
+		srv.Write([]byte(`[{"id":0,"result":8,"error":null},{"id":1,"result":8,"error":null}]` + "\n"))
+		srv.Write([]byte(`[{"id":2,"result":8,"error":null},{"id":3,"result":8,"error":null}]` + "\n"))
+		buf.ReadString('\n')
+		srv.Write([]byte(`{"id":4,"result":15,"error":null}` + "\n"))
+	}()
+
+	// Both timed out and canceled batches get late replies.
+	got := 42
+	b := client.Batch()
+	call := b.Call("Svc.Sum", [2]int{3, 5}, &got)
+	b.Call("Svc.Sum", [2]int{3, 5}, nil)
+	if err := b.Send(); !errors.Is(err, context.DeadlineExceeded) || call.Error != err {
+		t.Errorf("Send(), err = %v, call.Error = %v, want timeout", err, call.Error)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	b = client.Batch()
+	b.Call("Svc.Sum", [2]int{3, 5}, nil)
+	b.Call("Svc.Sum", [2]int{3, 5}, nil)
+	if err := b.SendContext(ctx); err != context.DeadlineExceeded {
+		t.Errorf("SendContext(), err = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	close(release)
+	var got2 int
+	if err := client.Call("Svc.SumAll", []int{10, 5}, &got2); err != nil || got2 != 15 {
+		t.Errorf("Call() = %v, %v, want 15, nil", got2, err)
+	}
+	if got != 42 {
+		t.Errorf("reply of canceled call = %d, want untouched 42", got)
+	}
+	if pending := codecPending(client); pending != 0 {
+		t.Errorf("len(pending) = %d, want 0", pending)
+	}
+	if pending := rpcPending(client); pending != 0 {
+		t.Errorf("len(rpc.Client.pending) = %d, want 0", pending)
+	}
+}
+
+func TestClientBatchHTTP(t *testing.T) {
+	ts := httptest.NewServer(HTTPHandler(nil))
+	defer ts.Close()
+	client := NewHTTPClient(ts.URL)
+	defer client.Close()
+
+	b := client.Batch()
+	var sum int
+	call1 := b.Call("Svc.Sum", [2]int{3, 5}, &sum)
+	call2 := b.Call("Svc.Err", struct{}{}, nil)
+	if err := b.Send(); err != nil {
+		t.Fatalf("Send(), err = %v", err)
+	}
+	if call1.Error != nil || sum != 8 {
+		t.Errorf("Svc.Sum = %v, %v, want 8, nil", sum, call1.Error)
+	}
+	if call2.Error == nil || !strings.Contains(call2.Error.Error(), "some issue") {
+		t.Errorf("Svc.Err, err = %v", call2.Error)
+	}
+
+	b = client.Batch()
+	b.Notify("Svc.Msg", [1]string{"http"})
+	if err := b.Send(); err != nil {
+		t.Errorf("Send() notifications, err = %v", err)
+	}
+	if msg := <-svcMsg; msg != "http" {
+		t.Errorf("Svc.Msg got %q, want %q", msg, "http")
+	}
+
+	bad := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer bad.Close()
+	client = NewHTTPClient(bad.URL)
+	defer client.Close()
+	b = client.Batch()
+	call1 = b.Call("Svc.Sum", [2]int{3, 5}, nil)
+	call2 = b.Call("Svc.Sum", [2]int{3, 5}, nil)
+	var terr *TransportError
+	if err := b.Send(); !errors.As(err, &terr) {
+		t.Errorf("Send() to bad server, err = %v, want *TransportError", err)
+	}
+	for _, call := range []*BatchCall{call1, call2} {
+		if call.Error == nil || !strings.Contains(call.Error.Error(), "bad HTTP") {
+			t.Errorf("call to bad server, err = %v", call.Error)
+		}
+	}
+}
+
+func TestCallError(t *testing.T) {
+	cli, srv := net.Pipe()
+	go ServeConn(srv)
+	client := NewClient(cli)
+
+	var rpcerr *Error
+	err := client.Call("Svc.Err3", struct{}{}, nil)
+	if !errors.As(err, &rpcerr) || rpcerr.Code != 42 || rpcerr.Message != "some issue" {
+		t.Fatalf("Svc.Err3, err = %#v", err)
+	}
+	var data map[string]int
+	if err := rpcerr.DecodeData(&data); err != nil || data["one"] != 1 || data["two"] != 2 {
+		t.Errorf("DecodeData() = %v, %v", data, err)
+	}
+
+	client.Close()
+	var terr *TransportError
+	err = client.Call("Svc.Sum", [2]int{3, 5}, nil)
+	if !errors.As(err, &terr) || !errors.Is(err, rpc.ErrShutdown) {
+		t.Errorf("Call() after Close(), err = %#v", err)
+	}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+	client = NewHTTPClient(ts.URL)
+	defer client.Close()
+	err = client.Call("Svc.Sum", [2]int{3, 5}, nil)
+	if !errors.As(err, &terr) || !strings.Contains(err.Error(), "bad HTTP") {
+		t.Errorf("Call() to bad server, err = %#v", err)
+	}
+}
+
+func TestServerErrorNoPanic(t *testing.T) {
+	cases := []struct {
+		err  error
+		want *Error
+	}{
+		{errors.New("not json"), NewError(errInternal.Code, "not json")},
+		{errors.New(`{"code":1`), NewError(errInternal.Code, `{"code":1`)},
+		{rpc.ErrShutdown, NewError(errInternal.Code, rpc.ErrShutdown.Error())},
+		{&TransportError{Err: errors.New("bad HTTP")}, NewError(errInternal.Code, "bad HTTP")},
+		{rpc.ServerError(`{"code":42,"message":"msg"}`), NewError(42, "msg")},
+	}
+	for _, c := range cases {
+		if got := ServerError(c.err); *got != *c.want {
+			t.Errorf("ServerError(%#v) = %v, want %v", c.err, got, c.want)
+		}
+	}
+}
+
+func TestHTTPClientWallet(t *testing.T) {
+	h := HTTPHandler(nil)
+	paths := make(chan string, 1)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, _ := r.BasicAuth(); user != "user" || pass != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		paths <- r.URL.EscapedPath()
+		h.ServeHTTP(w, r)
+	}))
+	defer ts.Close()
+	client := NewHTTPClient(ts.URL+"/", WithBasicAuth("user", "secret"))
+	defer client.Close()
+
+	wallet := func(c *Client, name string) *Client {
+		w, err := c.Wallet(name)
+		if err != nil {
+			t.Fatalf("Wallet(%q), err = %v", name, err)
+		}
+		return w
+	}
+	main := wallet(client, "main")
+	tests := []struct {
+		client *Client
+		want   string
+	}{
+		{client, "/"},
+		{main, "/wallet/main"},
+		{wallet(client, "my wallet/1"), "/wallet/my%20wallet%2F1"},
+		{wallet(main, "other"), "/wallet/other"},
+	}
+	for _, tc := range tests {
+		var got int
+		if err := tc.client.Call("Svc.Sum", [2]int{3, 5}, &got); err != nil || got != 8 {
+			t.Errorf("Call() = %v, %v, want 8, nil", got, err)
+			continue
+		}
+		if path := <-paths; path != tc.want {
+			t.Errorf("path = %q, want %q", path, tc.want)
+		}
+	}
+	if wallet(client, "main") != main || wallet(main, "main") != main {
+		t.Errorf("Wallet() returns new client for same wallet")
+	}
+
+	// Closed wallet is replaced by new client.
+	main.Close()
+	reopened := wallet(client, "main")
+	if reopened == main {
+		t.Errorf("Wallet() returns closed client")
+	}
+	var got int
+	if err := reopened.Call("Svc.Sum", [2]int{3, 5}, &got); err != nil || got != 8 {
+		t.Errorf("reopened wallet's Call() = %v, %v, want 8, nil", got, err)
+	} else if path := <-paths; path != "/wallet/main" {
+		t.Errorf("path = %q, want %q", path, "/wallet/main")
+	}
+
+	// Wallets are closed together with client.
+	client.Close()
+	if err := reopened.Call("Svc.Sum", [2]int{3, 5}, nil); !errors.Is(err, rpc.ErrShutdown) {
+		t.Errorf("wallet's Call() after Close(), err = %v", err)
+	}
+	if _, err := client.Wallet("new"); err != rpc.ErrShutdown {
+		t.Errorf("Wallet() after Close(), err = %v, want %v", err, rpc.ErrShutdown)
+	}
+
+	cli, srv := net.Pipe()
+	defer srv.Close()
+	tcpClient := NewClient(cli)
+	defer tcpClient.Close()
+	if _, err := tcpClient.Wallet("main"); err != ErrNotHTTP {
+		t.Errorf("Wallet() on TCP client, err = %v, want %v", err, ErrNotHTTP)
+	}
+}
+
+func TestHTTPClientWalletMaxInFlight(t *testing.T) {
+	h := HTTPHandler(nil)
+	started, unblock := make(chan struct{}, 16), make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		<-unblock
+		h.ServeHTTP(w, r)
+	}))
+	defer ts.Close()
+	client := NewHTTPClient(ts.URL, WithMaxInFlight(1, 0))
+	defer client.Close()
+	wallet, err := client.Wallet("main")
+	if err != nil {
+		t.Fatalf("Wallet(), err = %v", err)
+	}
+
+	errc := make(chan error, 1)
+	go func() { errc <- client.Call("Svc.Sum", [2]int{3, 5}, nil) }()
+	<-started
+	if err := wallet.Call("Svc.Sum", [2]int{3, 5}, nil); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("wallet's Call() with full queue, err = %v", err)
+	}
+	close(unblock)
+	if err := <-errc; err != nil {
+		t.Errorf("Call(), err = %v", err)
+	}
+	if err := wallet.Call("Svc.Sum", [2]int{3, 5}, nil); err != nil {
+		t.Errorf("wallet's Call(), err = %v", err)
+	}
+}
+
+func TestHTTPClientMaxInFlight(t *testing.T) {
+	h := HTTPHandler(nil)
+	var mu sync.Mutex
+	inflight, maxInFlight := 0, 0
+	started, unblock := make(chan struct{}, 16), make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		inflight++
+		if inflight > maxInFlight {
+			maxInFlight = inflight
+		}
+		mu.Unlock()
+		started <- struct{}{}
+		<-unblock
+		h.ServeHTTP(w, r)
+		mu.Lock()
+		inflight--
+		mu.Unlock()
+	}))
+	defer ts.Close()
+	client := NewHTTPClient(ts.URL, WithMaxInFlight(2, 1))
+	defer client.Close()
+
+	errc := make(chan error, 3)
+	for i := 0; i < 3; i++ {
+		go func() {
+			var got int
+			err := client.Call("Svc.Sum", [2]int{3, 5}, &got)
+			if err == nil && got != 8 {
+				err = fmt.Errorf("got %d, want 8", got)
+			}
+			errc <- err
+		}()
+	}
+	<-started
+	<-started
+	time.Sleep(20 * time.Millisecond) // let third call to be queued
+
+	var terr *TransportError
+	if err := client.Call("Svc.Sum", [2]int{3, 5}, nil); !errors.As(err, &terr) || !errors.Is(err, ErrQueueFull) {
+		t.Errorf("Call() with full queue, err = %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 0)
+	defer cancel()
+	if err := client.CallContext(ctx, "Svc.Sum", [2]int{3, 5}, nil); err != context.DeadlineExceeded {
+		t.Errorf("CallContext() with done ctx, err = %v", err)
+	}
+
+	close(unblock)
+	for i := 0; i < 3; i++ {
+		if err := <-errc; err != nil {
+			t.Errorf("Call(), err = %v", err)
+		}
+	}
+	if maxInFlight != 2 {
+		t.Errorf("max in flight = %d, want 2", maxInFlight)
+	}
+	if err := client.Call("Svc.Sum", [2]int{3, 5}, nil); err != nil {
+		t.Errorf("Call() after queue drained, err = %v", err)
+	}
+}
+
+func TestHTTPClientReplyMatching(t *testing.T) {
+	h := HTTPHandler(nil)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", contentType)
+		switch r.URL.Path {
+		case "/null":
+			fmt.Fprint(w, `{"id":null,"result":null,"error":{"code":-32700,"message":"Parse error"}}`)
+		case "/wrong":
+			fmt.Fprint(w, `{"id":12345,"result":8,"error":null}`)
+		case "/garbage":
+			fmt.Fprint(w, `<html>`)
+		default:
+			h.ServeHTTP(w, r)
+		}
+	}))
+	defer ts.Close()
+
+	tests := []struct {
+		path string
+		code int
+	}{
+		{"/null", -32700},
+		{"/wrong", errInternal.Code},
+		{"/garbage", errInternal.Code},
+	}
+	for _, tc := range tests {
+		client := NewHTTPClient(ts.URL + tc.path)
+		var rpcErr *Error
+		if err := client.Call("Svc.Sum", [2]int{3, 5}, nil); !errors.As(err, &rpcErr) || rpcErr.Code != tc.code {
+			t.Errorf("%s: Call(), err = %v, want code %d", tc.path, err, tc.code)
+		}
+		b := client.Batch()
+		call := b.Call("Svc.Sum", [2]int{3, 5}, nil)
+		if err := b.Send(); !errors.As(err, &rpcErr) || rpcErr.Code != tc.code || call.Error == nil {
+			t.Errorf("%s: Batch.Send(), err = %v, want code %d", tc.path, err, tc.code)
+		}
+		// Client still works.
+		if err := client.Notify("Svc.Sum", [2]int{3, 5}); err != nil {
+			t.Errorf("%s: Notify(), err = %v", tc.path, err)
+		}
+		client.Close()
+	}
+}
+
+func TestHTTPClientGet(t *testing.T) {
+	h := NewServer(nil, ServerHTTPGet("Svc.Sum"))
+	methods := make(chan string, 8)
+	var mu sync.Mutex
+	cache := make(map[string][]byte)
+	// Caching proxy uses URL as a key.
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() { methods <- r.Method }()
+		if r.Method != "GET" {
+			h.ServeHTTP(w, r)
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		cached := cache[r.URL.String()]
+		if cached == nil {
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, r)
+			cached = rec.Body.Bytes()
+			cache[r.URL.String()] = cached
+		}
+		w.Header().Set("Content-Type", contentType)
+		w.Write(cached)
+	}))
+	defer ts.Close()
+	client := NewHTTPClient(ts.URL, WithHTTPGet("Svc.Sum"))
+	defer client.Close()
+
+	for i := 0; i < 2; i++ {
+		var got int
+		if err := client.Call("Svc.Sum", [2]int{3, 5}, &got); err != nil || got != 8 {
+			t.Errorf("Call() = %v, %v, want 8, nil", got, err)
+		}
+	}
+	var name NameRes
+	if err := client.Call("Svc.Name", NameArg{"First", "Last"}, &name); err != nil {
+		t.Errorf("Call(Svc.Name), err = %v", err)
+	}
+	if err := client.Notify("Svc.Sum", [2]int{3, 5}); err != nil {
+		t.Errorf("Notify(), err = %v", err)
+	}
+	b := client.Batch()
+	b.Call("Svc.Sum", [2]int{3, 5}, nil)
+	if err := b.Send(); err != nil {
+		t.Errorf("Batch.Send(), err = %v", err)
+	}
+
+	want := []string{"GET", "GET", "POST", "POST", "POST"}
+	var got []string
+	for range want {
+		select {
+		case method := <-methods:
+			got = append(got, method)
+		case <-time.After(time.Second):
+			t.Fatalf("HTTP methods = %v, want %v", got, want)
+		}
+	}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("HTTP methods = %v, want %v", got, want)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(cache) != 1 {
+		t.Errorf("%d cached replies, want 1", len(cache))
+	}
+}
@@ -27,13 +27,233 @@ should return jsonrpc1.Error.
 Using positional parameters of different types
 
 If you'll have to provide method which should be called using positional
-parameters of different types then it's recommended to implement this
-using first parameter of custom type with json.Unmarshaler interface.
+parameters of different types then it's recommended to use first parameter
+of struct type with `jsonrpc:"pos=N"` field tags. Such method can be
+called both with positional (array) and named (object, using json field
+names) parameters. Fields tagged as optional or with default value (in
+JSON) may be omitted or null, missing (or null) required params result in
+error -32602 (Invalid params):
+
+	type GetBlockArg struct {
+		Hash      string `json:"blockhash" jsonrpc:"pos=0"`
+		Verbosity int    `json:"verbosity" jsonrpc:"pos=1,default=1"`
+	}
+
+	func (*Node) Getblock(arg GetBlockArg, res *Block) error
+
+Funcs registered in Registry may take such struct as their only param,
+in this case Registry.Register fails if struct has malformed tags (net/rpc
+methods with such param return error -32603 (Internal error) instead).
+For anything else implement first parameter of custom type with
+json.Unmarshaler interface.
 
 To call such a method you'll have to use client.Call() with []interface{}
 in args.
 
 
+Serving JSON-RPC 2.0
+
+Server speaks JSON-RPC 1.0 by default. Use ServerJSONRPC2 option with
+NewServer to serve JSON-RPC 2.0 instead:
+requests must contain "jsonrpc":"2.0" member, requests without "id"
+member are notifications and replies contain either "result" or "error"
+member. Both protocol versions support batch requests; batch containing
+only notifications gets no reply at all.
+
+
+Flat method names
+
+Net/rpc requires method names like "Service.Method", but bitcoind-family
+nodes use names like "getblockcount" or "z_getbalance", and Stratum uses
+names like "mining.subscribe". Use ServerDefaultService option to send
+requests without '.' to methods of given service, ServerCamelCase to turn
+snake_case names into names of exported Go methods, and
+ServerMethodAlias for names which don't fit:
+
+	srv := rpc.NewServer()
+	srv.Register(&Node{}) // implements Getblockcount, ZGetbalance, Info
+	http.Handle("/", jsonrpc1.NewServer(srv,
+		jsonrpc1.ServerDefaultService("Node"),
+		jsonrpc1.ServerCamelCase(),
+		jsonrpc1.ServerMethodAlias("getinfo", "Node.Info"),
+	))
+
+Use ServerMethodMapper for other mappings.
+
+
+Serving HTTP GET requests
+
+Server accepts only POST HTTP requests by default. Use ServerHTTPGet
+option to also accept GET requests with "method", "params" and "id" in
+URL query, optionally only for given (safe, read-only) methods:
+
+	http.Handle("/rpc", jsonrpc1.NewServer(nil, jsonrpc1.ServerHTTPGet("Status.Get")))
+
+This way status can be checked using browser or monitoring tools:
+/rpc?method=Status.Get&params=[]&id=1.
+
+Use WithHTTPGet option to make HTTP client send calls to given methods
+using GET requests, so HTTP proxies in front of server may cache
+replies. Notifications and batches are always sent using POST.
+
+
+Client protocol dialects
+
+Client speaks JSON-RPC 1.0 with extra "jsonrpc":"1.0" member by default,
+as expected by bitcoind-family nodes. Use WithDialect option with
+NewClient, Dial, NewHTTPClient or NewCustomHTTPClient to speak strict
+JSON-RPC 1.0 (JSONRPC10) or JSON-RPC 2.0 (JSONRPC20) instead. Replies are
+validated according to the chosen dialect.
+
+Real nodes often deviate from protocol spec, so client also use a Profile
+which declares tolerated quirks - like replies without "jsonrpc" member
+or JSON-RPC errors sent with HTTP status 500. Profiles
+for popular nodes (and ProfileStrict without any quirks) are available
+in registry, see LookupProfile. Use WithProfile option to choose profile
+(ProfileBitcoinCore by default).
+
+
+HTTP authentication and headers
+
+Use WithBasicAuth option with NewHTTPClient or NewCustomHTTPClient to
+authenticate using user and password (like rpcuser and rpcpassword).
+Use WithHeader option to add static headers (like API key) to all HTTP
+requests and ContextWithHeader to add headers (like X-Request-ID) to
+HTTP request of single call:
+
+	client := jsonrpc1.NewHTTPClient(url, jsonrpc1.WithHeader("X-Api-Key", key))
+	ctx := jsonrpc1.ContextWithHeader(ctx, "X-Request-ID", id)
+	err := client.CallContext(ctx, "getblockcount", nil, &count)
+
+Content-Type and Accept headers are always set by client itself.
+
+Use WithCookieFile option with NewHTTPClient or NewCustomHTTPClient to
+authenticate using cookie file created by bitcoind-family node on each
+start (like ~/.bitcoin/.cookie). When node replies with HTTP 401 client
+reads the file again and, if it has changed, resends request once, so
+restarted node won't break long-lived client.
+
+
+Multi-wallet nodes
+
+Bitcoind-family nodes serve wallet RPCs at /wallet/<name> endpoint. Use
+client.Wallet() to get client for wallet which shares HTTP transport and
+options (including credentials) with original client, and is closed
+together with it:
+
+	client := jsonrpc1.NewHTTPClient(url, jsonrpc1.WithCookieFile(path))
+	defer client.Close()
+	savings, err := client.Wallet("savings")
+	if err == nil {
+		err = savings.Call("getbalance", nil, &balance)
+	}
+
+
+Canceling calls, timeouts and retries
+
+Use client.CallContext() instead of client.Call() to be able to cancel a
+call. When ctx is done CallContext returns ctx.Err() immediately, HTTP
+request (if any) is aborted and late reply is ignored.
+
+Use WithTimeout and WithMethodTimeout options to limit duration of all
+calls made using client.Call() and client.CallContext() without creating
+context for each call. Expired timeout results in *TimeoutError.
+
+Use WithRetry option to retry failed calls with exponential backoff, for
+example while node is starting and replies with RPC_IN_WARMUP error:
+
+	client := jsonrpc1.NewHTTPClient(url, jsonrpc1.WithRetry(&jsonrpc1.RetryPolicy{
+		MaxAttempts:    10,
+		MaxElapsed:     time.Minute,
+		InitialBackoff: 100 * time.Millisecond,
+		MaxBackoff:     10 * time.Second,
+		Jitter:         0.2,
+		Methods:        []string{"getblockcount", "getblockhash", "getblock"},
+		Codes:          []int{jsonrpc1.CodeInWarmup},
+	}))
+
+Only calls to methods listed in RetryPolicy.Methods are retried.
+
+
+Long polling
+
+Use client.LongPoll() for calls which may wait for reply for minutes (like
+getblocktemplate with "longpollid"): it isn't limited by WithTimeout and
+HTTP client sends it using dedicated HTTP request, so it won't delay other
+calls (other clients send it over their connection as usual call). Use
+client.LongPollLoop() to repeat such call using "longpollid"
+from previous result until ctx is done.
+
+
+Limiting HTTP requests
+
+HTTP client sends each call using separate HTTP request, so burst of
+calls results in same amount of concurrent HTTP requests. Use
+WithMaxInFlight option to limit them: extra calls wait in queue, and
+calls which doesn't fit in queue fail with *TransportError wrapping
+ErrQueueFull. Each HTTP reply is matched to its request, so reply with
+missing or wrong id results in error for related call only.
+
+
+Size limits
+
+By default size of requests and replies isn't limited, so any peer can
+make server or client allocate a lot of memory. Use ServerMaxRequestSize
+and ServerMaxBatchLength options to limit requests accepted by server
+(it replies to larger ones with error -32600) and WithMaxResponseSize
+option to limit replies accepted by client. Larger reply is skipped while
+reading and only related call fails with *TransportError wrapping
+ErrResponseTooLarge.
+
+
+Failover between several nodes
+
+Use NewFailoverClient to send calls to first healthy of several nodes:
+
+	client := jsonrpc1.NewFailoverClient([]string{primaryURL, backupURL},
+		jsonrpc1.FailoverHealthCheck("getblockcount", nil, 10*time.Second),
+		jsonrpc1.FailoverOnSwitch(func(ev jsonrpc1.FailoverEvent) {
+			log.Printf("switched from %s to %s: %v", ev.From, ev.To, ev.Err)
+		}),
+	)
+
+It switches to next node when active one fails with *TransportError or
+*TimeoutError, and switches back to the primary when health check found
+it has recovered. Client, FailoverClient and other clients in this
+package implement Caller interface.
+
+
+Connection pool and reconnects
+
+Client created by Dial reads all replies from single connection, so huge
+reply delays replies to all other calls. Use DialPool to spread calls
+between several connections to same server:
+
+	pool, err := jsonrpc1.DialPool("tcp", address, 4)
+
+Use NewReconnectClient to get TCP client which dials again (with backoff)
+each time connection is lost. Use ReconnectOnState to get notified about
+connection state changes and ReconnectInFlight to choose what happens
+with calls when connection is lost.
+
+
+Client batch requests
+
+Use client.Batch() to collect calls and notifications and send them using
+single batch request (single HTTP request when using HTTP client):
+
+	b := client.Batch()
+	call1 := b.Call("Svc.Sum", [2]int{3, 5}, &sum)
+	call2 := b.Call("Svc.Name", NameArg{"First", "Last"}, &name)
+	b.Notify("Svc.Log", [1]string{"message"})
+	err := b.Send()
+
+Replies are matched to calls by id, so server may send them in any order.
+Error of each call is available in call1.Error, call2.Error, etc. Calls
+without reply get an error. If server replied with single error instead
+of array, or batch wasn't sent at all, then Send returns that error.
+
+
 Using context to provide transport-level details with parameters
 
 If you want to have access to transport-level details (or any other
@@ -45,25 +265,83 @@ This way you can get access to client IP address or details of client HTTP
 request etc. in RPC method.
 
 
+Registering funcs as RPC methods
+
+Use Registry with ServerRegistry option to serve plain funcs in addition
+to net/rpc methods. Func may get context and any amount of positional
+params, and its errors are sent as is (use *Error for custom codes):
+
+	reg := jsonrpc1.NewRegistry()
+	reg.Register("getblockhash", func(ctx context.Context, height int64) (string, error) {
+		return chain.BlockHash(ctx, height)
+	})
+	http.Handle("/rpc", jsonrpc1.NewServer(nil, jsonrpc1.ServerRegistry(reg)))
+
+
+Server interceptors
+
+Use ServerInterceptors option to run code around each call of RPC method
+(including each call in batch request) served by Server over connection
+or HTTP, e.g. for auth, logging or metrics. Interceptor gets method
+name, params, request ID and context, and either calls next handler to
+get result or error, or returns its own error without calling it:
+
+	auth := func(call *jsonrpc1.ServerCall, next jsonrpc1.ServerHandler) (interface{}, error) {
+		if !allowed(call.Context(), call.Method) {
+			return nil, jsonrpc1.NewError(-1, "access denied")
+		}
+		return next(call)
+	}
+	http.Handle("/rpc", jsonrpc1.NewServer(nil, jsonrpc1.ServerInterceptors(auth)))
+
+RPC method is called by next in same goroutine, so interceptor may also
+recover from panic in RPC method and return an error instead.
+
+
+Decoding numbers on client
+
+Results are decoded using json.Unmarshal, so numbers decoded into
+interface{} become float64 and may lose precision. Use WithUseNumber
+option to decode them as json.Number instead. Use Amount type to encode
+and decode coin amounts (like 0.00010000) in params and results exactly:
+
+	var balance jsonrpc1.Amount
+	err := client.Call("getbalance", nil, &balance)
+
+
+Request IDs
+
+Client sends requests with IDs 0, 1, 2, etc. Use WithIDGenerator option to
+send other IDs: PrefixedIDs (like "req-0", "req-1") and RandomIDs (UUIDs)
+are provided, or you can use your own IDGenerator. To send single call
+with given ID use ContextWithID:
+
+	ctx := jsonrpc1.ContextWithID(ctx, "my-id")
+	err := client.CallContext(ctx, "getblockcount", nil, &count)
+
+Call fails if its ID is already used by another call waiting for reply.
+
+
 Decoding errors on client
 
-Because of net/rpc limitations client.Call() can't return JSON-RPC 1.0
-error with code, message and extra data - it'll return either one of
-rpc.ErrShutdown or io.ErrUnexpectedEOF errors, or encoded JSON-RPC 1.0
-error, which have to be decoded using jsonrpc1.ServerError to get error's
-code, message and extra data.
+Errors returned by server are returned by client.Call() and
+client.CallContext() as *Error, use errors.As to get error's code, message
+and extra data (use Error.DecodeData to decode extra data into your type).
+Transport failures (including rpc.ErrShutdown and io.ErrUnexpectedEOF) are
+returned as *TransportError.
+
+Because of net/rpc limitations other net/rpc client methods (like
+client.Go()) return encoded JSON-RPC 1.0 error, which have to be decoded
+using jsonrpc1.ServerError to get error's code, message and extra data.
 
 
 Limitations
 
 HTTP client&server does not support Pipelined Requests/Responses.
 
-HTTP client&server does not support GET Request.
-
-HTTP client does not support Batch Request.
-
 Because of net/rpc limitations RPC method MUST NOT return standard
-error which begins with '{' and ends with '}'.
+error which begins with '{' and ends with '}' (funcs registered in
+Registry have no such limitation).
 
 Current implementation does a lot of sanity checks to conform to
 protocol spec. Making most of them optional may improve performance.
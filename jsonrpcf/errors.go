@@ -1,9 +1,15 @@
 package jsonrpcf
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"net"
+	"net/rpc"
 	"strings"
+	"time"
 )
 
 var (
@@ -44,13 +50,22 @@ func newError(message string) *Error {
 }
 
 // ServerError convert errors returned by Client.Call() into Error.
-// User should check for rpc.ErrShutdown and io.ErrUnexpectedEOF before
-// calling ServerError.
+//
+// Errors returned by Client.Call() and Client.CallContext() can be used
+// with errors.As instead, so ServerError is mostly useful for errors
+// returned by net/rpc Client methods like Go(). It never panics: errors
+// which can't be converted (including *TransportError) are returned as
+// Error with code -32603 and error's text as message.
 func ServerError(rpcerr error) *Error {
 	if rpcerr == nil {
 		return nil
 	}
-	if err, ok := rpcerr.(*Error); ok {
+	var terr *TransportError
+	if errors.As(rpcerr, &terr) {
+		return NewError(errInternal.Code, terr.Err.Error())
+	}
+	var err *Error
+	if errors.As(rpcerr, &err) {
 		if err.Code == errInternal.Code && err.Data != nil {
 			if err2, ok := err.Data.(*Error); ok {
 				// Use alternate error when ReadResponseBody fail on other call.
@@ -67,9 +82,8 @@ func ServerError(rpcerr error) *Error {
 		keepData = false
 	}
 	e := &Error{}
-	err := json.Unmarshal([]byte(errmsg), e)
-	if err != nil {
-		panic(fmt.Sprintf("not a jsonrpc1 error: %s (%#q)", err, rpcerr))
+	if json.Unmarshal([]byte(errmsg), e) != nil {
+		return NewError(errInternal.Code, rpcerr.Error())
 	}
 	if e.Code == errInternal.Code && e.Data != nil && !keepData {
 		// ReadResponseBody fail on this call.
@@ -78,6 +92,20 @@ func ServerError(rpcerr error) *Error {
 	return e
 }
 
+// DecodeData decodes e.Data into v. It's useful on client side, where
+// e.Data is decoded from JSON as generic value (map[string]interface{},
+// []interface{}, float64, etc.). Nothing is done if e.Data is nil.
+func (e *Error) DecodeData(v interface{}) error {
+	if e.Data == nil {
+		return nil
+	}
+	buf, err := json.Marshal(e.Data)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(buf, v)
+}
+
 // Error returns JSON representation of Error.
 func (e *Error) Error() string {
 	buf, err := json.Marshal(e)
@@ -90,3 +118,60 @@ func (e *Error) Error() string {
 	}
 	return string(buf)
 }
+
+// TimeoutError is returned by client.Call() and client.CallContext() when
+// client's timeout for a call has expired.
+type TimeoutError struct {
+	Method   string        // called method
+	Duration time.Duration // expired timeout
+}
+
+// Error returns error message.
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("%s: call timed out after %v", e.Method, e.Duration)
+}
+
+// Timeout returns true. It makes TimeoutError compatible with net.Error.
+func (e *TimeoutError) Timeout() bool { return true }
+
+// Temporary returns true. It makes TimeoutError compatible with net.Error.
+func (e *TimeoutError) Temporary() bool { return true }
+
+// Unwrap returns context.DeadlineExceeded.
+func (e *TimeoutError) Unwrap() error { return context.DeadlineExceeded }
+
+// TransportError is returned by client when request wasn't sent or reply
+// wasn't received because of transport failure: broken connection, HTTP
+// error, etc. Use errors.Is to check for wrapped error like
+// rpc.ErrShutdown.
+type TransportError struct {
+	Err error
+}
+
+// Error returns error message of wrapped error.
+func (e *TransportError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns wrapped error.
+func (e *TransportError) Unwrap() error { return e.Err }
+
+// transportError wraps err returned by net/rpc Client into TransportError
+// if it's a transport failure.
+func transportError(err error) error {
+	if err == rpc.ErrShutdown || err == io.ErrUnexpectedEOF {
+		return &TransportError{Err: err}
+	}
+	return err
+}
+
+// connLost returns true if call using c failed with err because c has
+// lost its connection (e.g. it was reset), so c should not be used anymore.
+func connLost(c *Client, err error) bool {
+	if errors.Is(err, rpc.ErrShutdown) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+	var terr *TransportError
+	var nerr net.Error
+	return c != nil && c.codec.isLost() && (errors.As(err, &terr) || errors.As(err, &nerr))
+}
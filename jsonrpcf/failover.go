@@ -0,0 +1,365 @@
+package jsonrpcf
+
+import (
+	"context"
+	"errors"
+	"net/rpc"
+	"sync"
+	"time"
+)
+
+// Caller is implemented by Client and other clients in this package, so
+// they can be used interchangeably.
+type Caller interface {
+	Call(serviceMethod string, args interface{}, reply interface{}) error
+	CallContext(ctx context.Context, serviceMethod string, args interface{}, reply interface{}) error
+	Notify(serviceMethod string, args interface{}) error
+	Close() error
+}
+
+var (
+	_ Caller = &Client{}
+	_ Caller = &FailoverClient{}
+)
+
+// FailoverEvent describes switch of FailoverClient to another endpoint.
+type FailoverEvent struct {
+	From string // previous active endpoint
+	To   string // new active endpoint
+	Err  error  // error which caused failover, nil on fail back
+}
+
+// FailoverOption configures FailoverClient created by NewFailoverClient.
+type FailoverOption func(*failoverConfig)
+
+type failoverConfig struct {
+	dial          func(endpoint string) (*Client, error)
+	clientOpts    []ClientOption
+	healthMethod  string
+	healthArgs    interface{}
+	healthEvery   time.Duration
+	healthTimeout time.Duration
+	onSwitch      func(FailoverEvent)
+}
+
+// FailoverClientOptions sets options for clients created by default dial
+// function, which use NewHTTPClient with endpoint as url.
+func FailoverClientOptions(opts ...ClientOption) FailoverOption {
+	return func(cfg *failoverConfig) {
+		cfg.clientOpts = opts
+	}
+}
+
+// FailoverDial sets function used to connect to endpoint, for example to
+// use TCP instead of HTTP:
+//
+//	jsonrpc1.FailoverDial(func(addr string) (*jsonrpc1.Client, error) {
+//		return jsonrpc1.Dial("tcp", addr)
+//	})
+//
+// Endpoint failed to connect is considered unhealthy, it'll be connected
+// again when needed.
+func FailoverDial(dial func(endpoint string) (*Client, error)) FailoverOption {
+	return func(cfg *failoverConfig) {
+		cfg.dial = dial
+	}
+}
+
+// FailoverHealthCheck makes client check health of all endpoints every
+// interval by calling method with args. Endpoint is healthy if call
+// succeed during interval.
+//
+// Without health checks client will fail over only on errors and will
+// never fail back to higher-priority endpoint.
+func FailoverHealthCheck(method string, args interface{}, interval time.Duration) FailoverOption {
+	return func(cfg *failoverConfig) {
+		cfg.healthMethod = method
+		cfg.healthArgs = args
+		cfg.healthEvery = interval
+		cfg.healthTimeout = interval
+	}
+}
+
+// FailoverOnSwitch sets callback called each time client switch to
+// another endpoint. Callback must not block.
+func FailoverOnSwitch(f func(FailoverEvent)) FailoverOption {
+	return func(cfg *failoverConfig) {
+		cfg.onSwitch = f
+	}
+}
+
+// FailoverClient sends calls to one of several endpoints (nodes) which
+// provide same services. Active endpoint is changed to next healthy one
+// when it fails to reply because of transport error or timeout, and
+// changed back to endpoint with higher priority when health check found
+// it has recovered.
+//
+// Call which has failed is not resent to another endpoint.
+type FailoverClient struct {
+	endpoints []string
+	cfg       *failoverConfig
+	done      chan struct{}
+
+	mu      sync.Mutex // protects following
+	clients []*Client  // nil if not connected
+	healthy []bool
+	active  int
+	closed  bool
+}
+
+// NewFailoverClient returns a new FailoverClient which use given
+// endpoints (at least one) in order of priority: first one is the
+// primary.
+//
+// By default endpoints are urls used to create clients with
+// NewHTTPClient, use FailoverDial to change this.
+//
+// If endpoints is empty then returned client is already closed: all calls
+// will fail with rpc.ErrShutdown.
+func NewFailoverClient(endpoints []string, opts ...FailoverOption) *FailoverClient {
+	cfg := &failoverConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	if cfg.dial == nil {
+		clientOpts := cfg.clientOpts
+		cfg.dial = func(endpoint string) (*Client, error) {
+			return NewHTTPClient(endpoint, clientOpts...), nil
+		}
+	}
+	f := &FailoverClient{
+		endpoints: endpoints,
+		cfg:       cfg,
+		done:      make(chan struct{}),
+		clients:   make([]*Client, len(endpoints)),
+		healthy:   make([]bool, len(endpoints)),
+	}
+	for i := range f.healthy {
+		f.healthy[i] = true
+	}
+	switch {
+	case len(endpoints) == 0:
+		f.closed = true
+		close(f.done)
+	case cfg.healthMethod != "" && cfg.healthEvery > 0:
+		go f.healthLoop()
+	}
+	return f
+}
+
+// Active returns current active endpoint, or empty string if there are
+// no endpoints.
+func (f *FailoverClient) Active() string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.endpoints) == 0 {
+		return ""
+	}
+	return f.endpoints[f.active]
+}
+
+// Call invokes the named function on active endpoint, waits for it to
+// complete, and returns its error status. It's CallContext with
+// background context.
+func (f *FailoverClient) Call(serviceMethod string, args interface{}, reply interface{}) error {
+	return f.CallContext(context.Background(), serviceMethod, args, reply)
+}
+
+// CallContext invokes the named function on active endpoint, waits for
+// it to complete, and returns its error status. See Client.CallContext.
+func (f *FailoverClient) CallContext(ctx context.Context, serviceMethod string, args interface{}, reply interface{}) error {
+	i := f.activeIndex()
+	c, err := f.client(i)
+	if err == nil {
+		err = c.CallContext(ctx, serviceMethod, args, reply)
+	}
+	if ctx.Err() == nil && isFailover(err) {
+		f.fail(i, c, err)
+	}
+	return err
+}
+
+// Notify try to invoke the named function on active endpoint. It return
+// error only in case it wasn't able to send request.
+func (f *FailoverClient) Notify(serviceMethod string, args interface{}) error {
+	i := f.activeIndex()
+	c, err := f.client(i)
+	if err == nil {
+		err = c.Notify(serviceMethod, args)
+	}
+	if isFailover(err) {
+		f.fail(i, c, err)
+	}
+	return err
+}
+
+// Close stops health checks and closes clients for all endpoints.
+func (f *FailoverClient) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed {
+		return rpc.ErrShutdown
+	}
+	f.closed = true
+	close(f.done)
+	for i, c := range f.clients {
+		if c != nil {
+			c.Close()
+			f.clients[i] = nil
+		}
+	}
+	return nil
+}
+
+// isFailover returns true if err should result in fail over.
+func isFailover(err error) bool {
+	var terr *TransportError
+	var timeout *TimeoutError
+	return errors.As(err, &terr) || errors.As(err, &timeout)
+}
+
+func (f *FailoverClient) activeIndex() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.active
+}
+
+// client returns client for endpoint i, connecting if needed.
+func (f *FailoverClient) client(i int) (*Client, error) {
+	f.mu.Lock()
+	if f.closed {
+		f.mu.Unlock()
+		return nil, &TransportError{Err: rpc.ErrShutdown}
+	}
+	c := f.clients[i]
+	f.mu.Unlock()
+	if c != nil {
+		return c, nil
+	}
+
+	c, err := f.cfg.dial(f.endpoints[i])
+	if err != nil {
+		return nil, &TransportError{Err: err}
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch {
+	case f.closed:
+		c.Close()
+		return nil, &TransportError{Err: rpc.ErrShutdown}
+	case f.clients[i] != nil:
+		c.Close()
+		return f.clients[i], nil
+	}
+	f.clients[i] = c
+	return c, nil
+}
+
+// drop forgets client c for endpoint i if it was shut down, so it'll be
+// connected again when needed.
+func (f *FailoverClient) drop(i int, c *Client, err error) {
+	if c == nil || !connLost(c, err) {
+		return
+	}
+	f.mu.Lock()
+	if f.clients[i] == c {
+		f.clients[i] = nil
+	}
+	f.mu.Unlock()
+	c.Close()
+}
+
+// fail marks endpoint i as unhealthy because of err and switches to next
+// healthy endpoint if i is active.
+func (f *FailoverClient) fail(i int, c *Client, err error) {
+	f.drop(i, c, err)
+
+	f.mu.Lock()
+	if f.closed {
+		f.mu.Unlock()
+		return
+	}
+	f.healthy[i] = false
+	if f.active != i {
+		f.mu.Unlock()
+		return
+	}
+	next := (i + 1) % len(f.endpoints)
+	for j := range f.healthy {
+		if f.healthy[j] {
+			next = j
+			break
+		}
+	}
+	f.active = next
+	f.mu.Unlock()
+
+	if next != i {
+		f.notify(FailoverEvent{From: f.endpoints[i], To: f.endpoints[next], Err: err})
+	}
+}
+
+func (f *FailoverClient) notify(ev FailoverEvent) {
+	if f.cfg.onSwitch != nil {
+		f.cfg.onSwitch(ev)
+	}
+}
+
+func (f *FailoverClient) healthLoop() {
+	ticker := time.NewTicker(f.cfg.healthEvery)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-f.done:
+			return
+		case <-ticker.C:
+			f.healthCheck()
+		}
+	}
+}
+
+// healthCheck checks all endpoints and switches to healthy endpoint with
+// highest priority.
+func (f *FailoverClient) healthCheck() {
+	healthy := make([]bool, len(f.endpoints))
+	var wg sync.WaitGroup
+	for i := range f.endpoints {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), f.cfg.healthTimeout)
+			defer cancel()
+			c, err := f.client(i)
+			if err == nil {
+				err = c.CallContext(ctx, f.cfg.healthMethod, f.cfg.healthArgs, nil)
+				f.drop(i, c, err)
+			}
+			healthy[i] = err == nil
+		}(i)
+	}
+	wg.Wait()
+
+	f.mu.Lock()
+	if f.closed {
+		f.mu.Unlock()
+		return
+	}
+	copy(f.healthy, healthy)
+	prev := f.active
+	for i := range healthy {
+		if healthy[i] && (i < prev || !healthy[prev]) {
+			f.active = i
+			break
+		}
+	}
+	cur := f.active
+	f.mu.Unlock()
+
+	if cur != prev {
+		var err error
+		if !healthy[prev] {
+			err = errors.New("health check failed")
+		}
+		f.notify(FailoverEvent{From: f.endpoints[prev], To: f.endpoints[cur], Err: err})
+	}
+}
@@ -0,0 +1,176 @@
+package jsonrpcf
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/rpc"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// switchableServer serves rpc.DefaultServer over HTTP or replies with
+// HTTP 503 while down is not 0.
+func switchableServer(down *int32) *httptest.Server {
+	h := HTTPHandler(nil)
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(down) != 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		h.ServeHTTP(w, r)
+	}))
+}
+
+// resetServer accepts TCP connections and resets first n of them after
+// receiving a request, other connections are served by rpc.DefaultServer.
+func resetServer(t *testing.T, n int) net.Listener {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		for i := 0; ; i++ {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			if i >= n {
+				go ServeConn(conn)
+				continue
+			}
+			go func() {
+				bufio.NewReader(conn).ReadBytes('\n')
+				conn.(*net.TCPConn).SetLinger(0)
+				conn.Close()
+			}()
+		}
+	}()
+	return ln
+}
+
+func TestFailoverClient(t *testing.T) {
+	var down1, down2 int32
+	ts1, ts2 := switchableServer(&down1), switchableServer(&down2)
+	defer ts1.Close()
+	defer ts2.Close()
+
+	events := make(chan FailoverEvent, 16)
+	client := NewFailoverClient([]string{ts1.URL, ts2.URL},
+		FailoverHealthCheck("Svc.Sum", [2]int{0, 0}, 20*time.Millisecond),
+		FailoverOnSwitch(func(ev FailoverEvent) { events <- ev }),
+	)
+	defer client.Close()
+
+	var got int
+	if err := client.Call("Svc.Sum", [2]int{3, 5}, &got); err != nil || got != 8 {
+		t.Errorf("Call() = %v, %v, want 8, nil", got, err)
+	}
+
+	atomic.StoreInt32(&down1, 1)
+	// Call may succeed if health check has switched endpoint already.
+	var terr *TransportError
+	if err := client.Call("Svc.Sum", [2]int{3, 5}, &got); err != nil && !errors.As(err, &terr) {
+		t.Errorf("Call() to failed primary, err = %v", err)
+	}
+	select {
+	case ev := <-events:
+		if ev.From != ts1.URL || ev.To != ts2.URL || ev.Err == nil {
+			t.Errorf("failover event = %+v", ev)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("no failover event")
+	}
+	if client.Active() != ts2.URL {
+		t.Errorf("Active() = %s, want %s", client.Active(), ts2.URL)
+	}
+	if err := client.Call("Svc.Sum", [2]int{3, 5}, &got); err != nil || got != 8 {
+		t.Errorf("Call() after failover = %v, %v, want 8, nil", got, err)
+	}
+
+	atomic.StoreInt32(&down1, 0)
+	select {
+	case ev := <-events:
+		if ev.From != ts2.URL || ev.To != ts1.URL || ev.Err != nil {
+			t.Errorf("fail back event = %+v", ev)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("no fail back event")
+	}
+	if client.Active() != ts1.URL {
+		t.Errorf("Active() = %s, want %s", client.Active(), ts1.URL)
+	}
+
+	client.Close()
+	if err := client.Call("Svc.Sum", [2]int{3, 5}, &got); !errors.Is(err, rpc.ErrShutdown) {
+		t.Errorf("Call() after Close(), err = %v", err)
+	}
+}
+
+func TestFailoverClientConnReset(t *testing.T) {
+	ln1 := resetServer(t, 1)
+	defer ln1.Close()
+	ln2, drop := tcpServer(t)
+	defer ln2.Close()
+	defer drop()
+
+	client := NewFailoverClient([]string{ln1.Addr().String(), ln2.Addr().String()},
+		FailoverDial(func(endpoint string) (*Client, error) { return Dial("tcp", endpoint) }),
+	)
+	defer client.Close()
+
+	var got int
+	var terr *TransportError
+	if err := client.Call("Svc.Sum", [2]int{3, 5}, &got); !errors.As(err, &terr) {
+		t.Errorf("Call() to reset endpoint, err = %v", err)
+	}
+	client.mu.Lock()
+	dropped := client.clients[0] == nil
+	client.mu.Unlock()
+	if !dropped {
+		t.Errorf("client for reset endpoint wasn't dropped")
+	}
+	if client.Active() != ln2.Addr().String() {
+		t.Errorf("Active() = %s, want %s", client.Active(), ln2.Addr())
+	}
+	if err := client.Call("Svc.Sum", [2]int{3, 5}, &got); err != nil || got != 8 {
+		t.Errorf("Call() after failover = %v, %v, want 8, nil", got, err)
+	}
+}
+
+func TestFailoverClientServerError(t *testing.T) {
+	var down int32
+	ts1, ts2 := switchableServer(&down), switchableServer(&down)
+	defer ts1.Close()
+	defer ts2.Close()
+
+	client := NewFailoverClient([]string{ts1.URL, ts2.URL})
+	defer client.Close()
+
+	// Errors returned by RPC method doesn't mean endpoint is down.
+	if err := client.Call("Svc.Err2", struct{}{}, nil); err == nil {
+		t.Errorf("Svc.Err2, err = nil")
+	}
+	if client.Active() != ts1.URL {
+		t.Errorf("Active() = %s, want %s", client.Active(), ts1.URL)
+	}
+}
+
+func TestFailoverClientNoEndpoints(t *testing.T) {
+	client := NewFailoverClient(nil, FailoverHealthCheck("Svc.Sum", [2]int{3, 5}, time.Millisecond))
+	if client.Active() != "" {
+		t.Errorf("Active() = %s, want empty", client.Active())
+	}
+	if err := client.Call("Svc.Sum", [2]int{3, 5}, nil); !errors.Is(err, rpc.ErrShutdown) {
+		t.Errorf("Call(), err = %v, want %v", err, rpc.ErrShutdown)
+	}
+	if err := client.Notify("Svc.Msg", [1]string{"test"}); !errors.Is(err, rpc.ErrShutdown) {
+		t.Errorf("Notify(), err = %v, want %v", err, rpc.ErrShutdown)
+	}
+	if err := client.Close(); err != rpc.ErrShutdown {
+		t.Errorf("Close(), err = %v, want %v", err, rpc.ErrShutdown)
+	}
+}
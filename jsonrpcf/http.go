@@ -3,20 +3,29 @@ package jsonrpcf
 import (
 	"bytes"
 	"context"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"mime"
 	"net/http"
 	"net/rpc"
+	"net/url"
+	"strings"
+	"sync"
 )
 
 const contentType = "application/json"
 
 type contextKey int
 
-var httpRequestContextKey contextKey = 0
+const (
+	httpRequestContextKey contextKey = iota
+	httpHeaderContextKey
+	requestIDContextKey
+)
 
 var rpc_debug = false
 
@@ -31,6 +40,35 @@ func HTTPRequestFromContext(ctx context.Context) *http.Request {
 	return req
 }
 
+// WithHeader makes HTTP client add header key with value to all HTTP
+// requests. Content-Type and Accept headers can't be changed.
+func WithHeader(key, value string) ClientOption {
+	return func(cfg *clientConfig) {
+		if cfg.header == nil {
+			cfg.header = make(http.Header)
+		}
+		cfg.header.Add(key, value)
+	}
+}
+
+// ContextWithHeader returns copy of ctx which makes HTTP client add
+// header key with value to HTTP request sent by client.CallContext() or
+// Batch.SendContext() with this ctx, in addition to headers set by
+// WithHeader and previous ContextWithHeader calls.
+func ContextWithHeader(ctx context.Context, key, value string) context.Context {
+	header := headerFromContext(ctx).Clone()
+	if header == nil {
+		header = make(http.Header)
+	}
+	header.Add(key, value)
+	return context.WithValue(ctx, httpHeaderContextKey, header)
+}
+
+func headerFromContext(ctx context.Context) http.Header {
+	header, _ := ctx.Value(httpHeaderContextKey).(http.Header)
+	return header
+}
+
 type httpServerConn struct {
 	req     io.Reader
 	res     io.Writer
@@ -50,39 +88,111 @@ func (conn *httpServerConn) Close() error {
 	return nil
 }
 
-type httpHandler struct {
-	rpc *rpc.Server
-}
-
 // HTTPHandler returns handler for HTTP requests which will execute
 // incoming JSON-RPC 1.0 over HTTP using srv.
 //
 // If srv is nil then rpc.DefaultServer will be used.
 //
+// Use NewServer to get handler configured by ServerOption: Server is an
+// http.Handler too.
+//
 // Specification: http://www.simple-is-better.org/json-rpc/transport_http.html
 func HTTPHandler(srv *rpc.Server) http.Handler {
-	if srv == nil {
-		srv = rpc.DefaultServer
+	return NewServer(srv)
+}
+
+// ServerHTTPGet makes Server accept HTTP GET requests with "method",
+// "params" and "id" in URL query, as defined by specification. Params
+// must be JSON array or object, optionally encoded using base64 (as
+// suggested by specification). Request without "id" is a notification.
+//
+// If methods are given then only these methods can be called using GET
+// (intended for safe read-only methods), others result in HTTP 405.
+func ServerHTTPGet(methods ...string) ServerOption {
+	return func(cfg *serverConfig) {
+		cfg.httpGet = true
+		if len(methods) > 0 {
+			cfg.getMethods = make(map[string]bool)
+			for _, method := range methods {
+				cfg.getMethods[method] = true
+			}
+		}
 	}
-	return &httpHandler{srv}
 }
 
-func (h *httpHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+// httpGetRequest is a request built from URL query of HTTP GET request.
+type httpGetRequest struct {
+	Version string           `json:"jsonrpc,omitempty"`
+	Method  string           `json:"method"`
+	Params  *json.RawMessage `json:"params,omitempty"`
+	ID      *json.RawMessage `json:"id,omitempty"`
+}
+
+// getRequest returns JSON-encoded request from URL query of HTTP GET
+// request, or nil if it isn't valid JSON.
+func (s *Server) getRequest(query url.Values) []byte {
+	r := httpGetRequest{Method: query.Get("method")}
+	if s.cfg.jsonrpc2 {
+		r.Version = "2.0"
+	}
+	if _, ok := query["params"]; ok {
+		params := []byte(query.Get("params"))
+		if len(params) > 0 && params[0] != '[' && params[0] != '{' {
+			buf, err := base64.URLEncoding.DecodeString(string(params))
+			if err != nil {
+				buf, err = base64.StdEncoding.DecodeString(string(params))
+			}
+			if err != nil {
+				return nil
+			}
+			params = buf
+		}
+		r.Params = (*json.RawMessage)(&params)
+	}
+	if _, ok := query["id"]; ok {
+		id := json.RawMessage(query.Get("id"))
+		r.ID = &id
+	}
+	buf, err := json.Marshal(r) // fails on invalid params or id
+	if err != nil {
+		return nil
+	}
+	return buf
+}
+
+// ServeHTTP executes JSON-RPC request sent using HTTP request.
+func (s *Server) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 	w.Header().Set("Content-Type", contentType)
 
-	if req.Method != "POST" {
+	var body io.Reader = req.Body
+	switch {
+	case req.Method == "GET" && s.cfg.httpGet:
+		query := req.URL.Query()
+		if s.cfg.getMethods != nil && !s.cfg.getMethods[query.Get("method")] {
+			w.Header().Set("Allow", "POST")
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		buf := s.getRequest(query)
+		if buf == nil {
+			json.NewEncoder(w).Encode(s.cfg.response(&null, nil, errParse))
+			return
+		}
+		body = bytes.NewReader(buf)
+	case req.Method != "POST":
 		w.WriteHeader(http.StatusMethodNotAllowed)
 		return
-	}
-	mediaType, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type"))
-	if mediaType != contentType || req.Header.Get("Accept") != contentType {
-		w.WriteHeader(http.StatusUnsupportedMediaType)
-		return
+	default:
+		mediaType, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type"))
+		if mediaType != contentType || req.Header.Get("Accept") != contentType {
+			w.WriteHeader(http.StatusUnsupportedMediaType)
+			return
+		}
 	}
 
 	ctx := context.WithValue(context.Background(), httpRequestContextKey, req)
-	conn := &httpServerConn{req: req.Body, res: w}
-	h.rpc.ServeRequest(NewServerCodecContext(ctx, conn, h.rpc))
+	conn := &httpServerConn{req: body, res: w}
+	s.rpc.ServeRequest(newServerCodec(ctx, conn, s.rpc, s.cfg))
 	if !conn.replied {
 		w.WriteHeader(http.StatusNoContent)
 	}
@@ -103,103 +213,369 @@ func (f DoerFunc) Do(req *http.Request) (resp *http.Response, err error) {
 	return f(req)
 }
 
+// ErrQueueFull is wrapped in *TransportError returned by HTTP client when
+// limits set by WithMaxInFlight are reached.
+var ErrQueueFull = errors.New("too many requests in flight")
+
+// WithMaxInFlight limits HTTP client to limit concurrent HTTP requests
+// (no limit by default). Up to queue more requests will wait until one of
+// requests in flight will finish (or until their ctx is done), others
+// fail with *TransportError wrapping ErrQueueFull.
+//
+// Request finishes when its reply is read by client, so reading replies
+// slowly (e.g. because of huge replies) delays new requests too.
+func WithMaxInFlight(limit, queue int) ClientOption {
+	return func(cfg *clientConfig) {
+		cfg.maxInFlight = limit
+		cfg.maxQueued = queue
+	}
+}
+
+// WithHTTPGet makes HTTP client send calls (but not notifications and
+// batches) to given methods using HTTP GET requests with "method",
+// "params" and "id" in URL query (see ServerHTTPGet), so replies can be
+// cached by HTTP proxies. Use it only for safe read-only methods.
+//
+// Same "id" is used in all HTTP GET requests, so same call always has
+// same URL. Client ignores id in replies to HTTP GET requests.
+func WithHTTPGet(methods ...string) ClientOption {
+	return func(cfg *clientConfig) {
+		if cfg.getMethods == nil {
+			cfg.getMethods = make(map[string]bool)
+		}
+		for _, method := range methods {
+			cfg.getMethods[method] = true
+		}
+	}
+}
+
+// HTTPError is wrapped in *TransportError returned by HTTP client when
+// server replied with unexpected HTTP status.
+type HTTPError struct {
+	StatusCode int    // e.g. 503
+	Status     string // e.g. "503 Service Unavailable"
+}
+
+// Error returns error message.
+func (e *HTTPError) Error() string {
+	return "bad HTTP Status: " + e.Status
+}
+
 type httpClientConn struct {
 	url   string
+	root  string // url without wallet path
 	doer  Doer
-	ready chan io.ReadCloser
-	body  io.ReadCloser
+	cfg   *clientConfig
+	codec *clientCodec
+	slots *httpSlots      // shared with wallets, nil if not limited
+	owner *httpClientConn // conn which owns this wallet's conn, if any
+	name  string          // wallet name, if owner is set
+	body  *bytes.Reader   // reply being read by Read
+	once  sync.Once
+	done  chan struct{} // closed by Close
+
+	mu      sync.Mutex         // protects following
+	replies []httpReply        // replies not read yet
+	signal  chan struct{}      // has value when replies was added
+	wallets map[string]*Client // clients returned by Wallet
 }
 
-func (conn *httpClientConn) Read(buf []byte) (int, error) {
-	if conn.body == nil {
-		conn.body = <-conn.ready
-	}
-	n, err := conn.body.Read(buf)
-	if err == io.EOF {
-		conn.body.Close()
-		conn.body = nil
-		err = nil
-		if n == 0 {
-			return conn.Read(buf)
+// httpSlots limits amount of requests in flight.
+type httpSlots struct {
+	ch chan struct{} // requests in flight
+
+	mu      sync.Mutex // protects following
+	pending int        // requests in flight or waiting for slot
+}
+
+// httpReply is a body of HTTP reply to request with given ids.
+type httpReply struct {
+	ids  []uint64
+	body []byte
+}
+
+// readMessage returns next reply with ids of related requests. It
+// returns io.EOF after Close.
+func (conn *httpClientConn) readMessage() ([]byte, []uint64, error) {
+	for {
+		conn.mu.Lock()
+		if len(conn.replies) > 0 {
+			reply := conn.replies[0]
+			conn.replies[0] = httpReply{}
+			conn.replies = conn.replies[1:]
+			conn.mu.Unlock()
+			conn.release()
+			if rpc_debug {
+				fmt.Printf("DEBUG(R): %v %s\n", reply.ids, reply.body)
+			}
+			return reply.body, reply.ids, nil
+		}
+		conn.mu.Unlock()
+		select {
+		case <-conn.signal:
+		case <-conn.done:
+			return nil, nil, io.EOF
 		}
 	}
+}
 
-	if rpc_debug {
-		fmt.Printf("DEBUG(R): %d %d %s\n", n, len(buf), buf[0:n])
+func (conn *httpClientConn) Read(buf []byte) (int, error) {
+	for conn.body == nil || conn.body.Len() == 0 {
+		msg, _, err := conn.readMessage()
+		if err != nil {
+			return 0, err
+		}
+		conn.body = bytes.NewReader(msg)
 	}
-
-	return n, err
+	return conn.body.Read(buf)
 }
 
 func (conn *httpClientConn) Write(buf []byte) (int, error) {
+	return conn.WriteContext(context.Background(), buf)
+}
+
+// WriteContext sends request in buf using HTTP request with given ctx.
+func (conn *httpClientConn) WriteContext(ctx context.Context, buf []byte) (int, error) {
 	if rpc_debug {
 		fmt.Printf("DEBUG(W): %s\n", buf)
 	}
 
+	if conn.slots != nil {
+		conn.slots.mu.Lock()
+		full := conn.slots.pending >= cap(conn.slots.ch)+conn.cfg.maxQueued
+		if !full {
+			conn.slots.pending++
+		}
+		conn.slots.mu.Unlock()
+		if full {
+			return 0, ErrQueueFull
+		}
+	}
 	b := make([]byte, len(buf))
 	copy(b, buf)
+	wireIDs := requestIDs(b)
+	ids := conn.codec.idsOf(wireIDs)
 	go func() {
-		req, err := http.NewRequest("POST", conn.url, bytes.NewReader(b))
-		if err == nil {
-			req.Header.Add("Content-Type", contentType)
-			req.Header.Add("Accept", contentType)
-			var resp *http.Response
-			resp, err = conn.doer.Do(req)
-			const maxBodySlurpSize = 32 * 1024
-			if err != nil {
-			} else if strings.Split(resp.Header.Get("Content-Type"),";")[0] != contentType {
-				err = fmt.Errorf("bad HTTP Content-Type: %s", resp.Header.Get("Content-Type"))
-			} else if resp.StatusCode == http.StatusOK {
-				conn.ready <- resp.Body
+		if conn.slots != nil {
+			select {
+			case conn.slots.ch <- struct{}{}:
+			case <-ctx.Done(): // call has returned ctx.Err() already
+				conn.slots.mu.Lock()
+				conn.slots.pending--
+				conn.slots.mu.Unlock()
 				return
-			} else if resp.StatusCode == http.StatusNoContent || resp.StatusCode == http.StatusAccepted {
-				// Read the body if small so underlying TCP connection will be re-used.
-				// No need to check for errors: if it fails, Transport won't reuse it anyway.
-				if resp.ContentLength == -1 || resp.ContentLength <= maxBodySlurpSize {
-					io.CopyN(ioutil.Discard, resp.Body, maxBodySlurpSize)
-				}
-				resp.Body.Close()
-				return
-			} else if resp.StatusCode == http.StatusInternalServerError {
-			        //!!!FIX!!! Workaround for invalid Zcash's JSONRPC implementation: they return StatusInternalServerError for some error reply			        
-                          	conn.ready <- resp.Body
+			case <-conn.done:
 				return
-			} else if resp.StatusCode == http.StatusNotFound {
-			        //!!!FIX!!! Workaround for invalid Zcash's JSONRPC implementation: they return StatusInternalServerError for some error reply			        
-                          	conn.ready <- resp.Body
-				return
-			} else {
-				err = fmt.Errorf("bad HTTP Status: %s", resp.Status)
 			}
-			if resp != nil {
-				// Read the body if small so underlying TCP connection will be re-used.
-				// No need to check for errors: if it fails, Transport won't reuse it anyway.
-				if resp.ContentLength == -1 || resp.ContentLength <= maxBodySlurpSize {
-					io.CopyN(ioutil.Discard, resp.Body, maxBodySlurpSize)
-				}
+		}
+		getURL := conn.getURL(b)
+		resp, err := conn.do(ctx, b, getURL)
+		switch {
+		case err != nil:
+		case !conn.cfg.profile.httpReply(resp.StatusCode) &&
+			resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusAccepted:
+			err = &HTTPError{StatusCode: resp.StatusCode, Status: resp.Status}
+		case strings.Split(resp.Header.Get("Content-Type"), ";")[0] != contentType:
+			err = fmt.Errorf("bad HTTP Content-Type: %s", resp.Header.Get("Content-Type"))
+		case conn.cfg.profile.httpReply(resp.StatusCode):
+			// Read whole body right now: it can't be read after
+			// ctx is done, which may happen before codec read it.
+			var body []byte
+			if body, err = conn.readBody(resp); err == nil {
 				resp.Body.Close()
+				if getURL != "" {
+					body = replaceID(body, wireIDs[0])
+				}
+				conn.push(ids, body)
+				return
 			}
+		default: // No reply to notification.
+			discardBody(resp)
+			conn.release()
+			return
+		}
+		if resp != nil {
+			discardBody(resp)
 		}
-		var res clientResponse
-		if json.Unmarshal(b, &res) == nil && res.ID == nil {
-			return // ignore error from Notification
+		if reply := conn.codec.failed(b, err); reply != nil {
+			conn.push(ids, reply)
+		} else {
+			conn.release()
 		}
-		res.Error = NewError(errInternal.Code, err.Error())
-		buf := &bytes.Buffer{}
-		json.NewEncoder(buf).Encode(res)
-		conn.ready <- ioutil.NopCloser(buf)
 	}()
 	return len(buf), nil
 }
 
+// push makes reply to requests with given ids available for readMessage.
+// Request's slot will be released when reply will be read.
+func (conn *httpClientConn) push(ids []uint64, body []byte) {
+	conn.mu.Lock()
+	conn.replies = append(conn.replies, httpReply{ids: ids, body: body})
+	conn.mu.Unlock()
+	select {
+	case conn.signal <- struct{}{}:
+	default:
+	}
+}
+
+// release frees slot of finished request.
+func (conn *httpClientConn) release() {
+	if conn.slots == nil {
+		return
+	}
+	conn.slots.mu.Lock()
+	conn.slots.pending--
+	conn.slots.mu.Unlock()
+	<-conn.slots.ch
+}
+
+// do sends request b using HTTP POST request, or HTTP GET request to
+// getURL if it's not empty. If server replied with HTTP 401 and cookie
+// file has changed then request will be sent again.
+func (conn *httpClientConn) do(ctx context.Context, b []byte, getURL string) (*http.Response, error) {
+	resp, auth, err := conn.send(ctx, b, getURL)
+	if err == nil && resp.StatusCode == http.StatusUnauthorized && conn.cfg.cookie != nil {
+		if conn.cfg.cookie.reload() && conn.cfg.cookie.auth() != auth {
+			discardBody(resp)
+			resp, _, err = conn.send(ctx, b, getURL)
+		}
+	}
+	return resp, err
+}
+
+// send sends request b using HTTP POST request, or HTTP GET request to
+// getURL if it's not empty. It returns used cookie auth.
+func (conn *httpClientConn) send(ctx context.Context, b []byte, getURL string) (*http.Response, string, error) {
+	var req *http.Request
+	var err error
+	if getURL != "" {
+		req, err = http.NewRequest("GET", getURL, nil)
+	} else {
+		req, err = http.NewRequest("POST", conn.url, bytes.NewReader(b))
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	req = req.WithContext(ctx)
+	for _, header := range []http.Header{conn.cfg.header, headerFromContext(ctx)} {
+		for key, values := range header {
+			for _, value := range values {
+				req.Header.Add(key, value)
+			}
+		}
+	}
+	if getURL == "" {
+		req.Header.Set("Content-Type", contentType)
+	} else {
+		req.Header.Del("Content-Type")
+	}
+	req.Header.Set("Accept", contentType)
+	var auth string
+	switch {
+	case conn.cfg.basicAuth != nil:
+		req.SetBasicAuth(conn.cfg.basicAuth[0], conn.cfg.basicAuth[1])
+	case conn.cfg.cookie != nil:
+		if auth, err = conn.cfg.cookie.setAuth(req); err != nil {
+			return nil, "", err
+		}
+	}
+	resp, err := conn.doer.Do(req)
+	return resp, auth, err
+}
+
+// getURL returns URL for sending request b using HTTP GET request, or ""
+// if it should be sent using HTTP POST request.
+func (conn *httpClientConn) getURL(b []byte) string {
+	if conn.cfg.getMethods == nil || len(b) == 0 || b[0] != '{' {
+		return ""
+	}
+	var req struct {
+		Method string           `json:"method"`
+		Params json.RawMessage  `json:"params"`
+		ID     *json.RawMessage `json:"id"`
+	}
+	if json.Unmarshal(b, &req) != nil || req.ID == nil || !conn.cfg.getMethods[req.Method] {
+		return ""
+	}
+	u, err := url.Parse(conn.url)
+	if err != nil {
+		return ""
+	}
+	query := u.Query()
+	query.Set("method", req.Method)
+	if req.Params != nil {
+		query.Set("params", string(req.Params))
+	}
+	query.Set("id", "1") // same URL for same call, id in reply is replaced
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+// replaceID returns reply in buf with id replaced by given one, so reply
+// cached by HTTP proxy for same request with another id will match it.
+func replaceID(buf []byte, id json.RawMessage) []byte {
+	var reply map[string]json.RawMessage
+	if json.Unmarshal(buf, &reply) != nil || reply["id"] == nil || string(reply["id"]) == "null" {
+		return buf
+	}
+	reply["id"] = id
+	out, err := json.Marshal(reply)
+	if err != nil {
+		return buf
+	}
+	return out
+}
+
+// readBody reads whole resp.Body, limiting its size according to
+// WithMaxResponseSize.
+func (conn *httpClientConn) readBody(resp *http.Response) ([]byte, error) {
+	max := conn.cfg.maxResponseSize
+	if max <= 0 {
+		return ioutil.ReadAll(resp.Body)
+	}
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, max+1))
+	if err == nil && int64(len(body)) > max {
+		err = ErrResponseTooLarge
+	}
+	return body, err
+}
+
+// discardBody closes resp.Body. It reads the body if small so underlying
+// TCP connection will be re-used.
+func discardBody(resp *http.Response) {
+	const maxBodySlurpSize = 32 * 1024
+	// No need to check for errors: if it fails, Transport won't reuse it anyway.
+	if resp.ContentLength == -1 || resp.ContentLength <= maxBodySlurpSize {
+		io.CopyN(ioutil.Discard, resp.Body, maxBodySlurpSize)
+	}
+	resp.Body.Close()
+}
+
 func (conn *httpClientConn) Close() error {
+	conn.once.Do(func() {
+		close(conn.done)
+		conn.mu.Lock()
+		wallets := conn.wallets
+		conn.wallets = nil
+		conn.mu.Unlock()
+		for _, client := range wallets {
+			client.Close()
+		}
+		if owner := conn.owner; owner != nil {
+			owner.mu.Lock()
+			if client := owner.wallets[conn.name]; client != nil && client.codec == conn.codec {
+				delete(owner.wallets, conn.name)
+			}
+			owner.mu.Unlock()
+		}
+	})
 	return nil
 }
 
 // NewHTTPClient returns a new Client to handle requests to the
 // set of services at the given url.
-func NewHTTPClient(url string) *Client {
-	return NewCustomHTTPClient(url, nil)
+func NewHTTPClient(url string, opts ...ClientOption) *Client {
+	return NewCustomHTTPClient(url, nil, opts...)
 }
 
 // NewCustomHTTPClient returns a new Client to handle requests to the
@@ -209,13 +585,88 @@ func NewHTTPClient(url string) *Client {
 // Use doer to customize HTTP authorization/headers/etc. sent with each
 // request (it method Do() will receive already configured POST request
 // with url, all required headers and body set according to specification).
-func NewCustomHTTPClient(url string, doer Doer) *Client {
+func NewCustomHTTPClient(url string, doer Doer, opts ...ClientOption) *Client {
 	if doer == nil {
 		doer = &http.Client{}
 	}
-	return NewClient(&httpClientConn{
-		url:   url,
-		doer:  doer,
-		ready: make(chan io.ReadCloser, 16),
-	})
+	cfg := newClientConfig(opts)
+	return newHTTPClient(url, url, doer, cfg, newHTTPSlots(cfg))
+}
+
+// newHTTPSlots returns limit of requests in flight set by WithMaxInFlight,
+// or nil if not limited.
+func newHTTPSlots(cfg *clientConfig) *httpSlots {
+	if cfg.maxInFlight <= 0 {
+		return nil
+	}
+	return &httpSlots{ch: make(chan struct{}, cfg.maxInFlight)}
+}
+
+func newHTTPClient(url, root string, doer Doer, cfg *clientConfig, slots *httpSlots) *Client {
+	conn := &httpClientConn{
+		url:    url,
+		root:   root,
+		doer:   doer,
+		cfg:    cfg,
+		slots:  slots,
+		done:   make(chan struct{}),
+		signal: make(chan struct{}, 1),
+	}
+	client := newClient(conn, cfg)
+	conn.codec = client.codec
+	return client
+}
+
+// ErrNotHTTP is returned by Wallet called on non-HTTP client.
+var ErrNotHTTP = errors.New("not an HTTP client")
+
+// Wallet returns Client to handle requests to wallet with given name
+// using url/wallet/<name> endpoint (as supported by bitcoind-family
+// nodes). It uses same doer and options (including credentials) as c and
+// shares c's limit of requests in flight (see WithMaxInFlight). Calling
+// Wallet on wallet's client returns client for another wallet.
+//
+// Wallet's client is created once per name and closed together with c,
+// so caller doesn't need to close it. After wallet's client was closed
+// next call to Wallet with same name returns new client.
+//
+// Wallet returns ErrNotHTTP if c wasn't returned by NewHTTPClient,
+// NewCustomHTTPClient or Wallet, and rpc.ErrShutdown if c is closed.
+func (c *Client) Wallet(name string) (*Client, error) {
+	conn, ok := c.codec.c.(*httpClientConn)
+	if !ok {
+		return nil, ErrNotHTTP
+	}
+	if conn.owner != nil {
+		conn = conn.owner
+	}
+	conn.mu.Lock()
+	defer conn.mu.Unlock()
+	select {
+	case <-conn.done:
+		return nil, rpc.ErrShutdown
+	default:
+	}
+	if client := conn.wallets[name]; client != nil {
+		return client, nil
+	}
+	client := newHTTPClient(walletURL(conn.root, name), conn.root, conn.doer, conn.cfg, conn.slots)
+	wallet := client.codec.c.(*httpClientConn)
+	wallet.owner, wallet.name = conn, name
+	if conn.wallets == nil {
+		conn.wallets = make(map[string]*Client)
+	}
+	conn.wallets[name] = client
+	return client, nil
+}
+
+// walletURL returns url of endpoint for wallet with given name.
+func walletURL(root, name string) string {
+	u, err := url.Parse(root)
+	if err != nil { // Request to root will fail too.
+		return strings.TrimSuffix(root, "/") + "/wallet/" + url.PathEscape(name)
+	}
+	u.RawPath = strings.TrimSuffix(u.EscapedPath(), "/") + "/wallet/" + url.PathEscape(name)
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/wallet/" + name
+	return u.String()
 }
@@ -105,6 +105,62 @@ func TestHTTPServer(t *testing.T) {
 	}
 }
 
+func TestHTTPServerGet(t *testing.T) {
+	const jRes = `{"id":1,"result":8,"error":null}`
+	const jRes2 = `{"jsonrpc":"2.0","id":"a","result":8}`
+	const jParse = `{"id":null,"error":{"code":-32700,"message":"Parse error"}}`
+
+	cases := []struct {
+		opts  []jsonrpc1.ServerOption
+		query string
+		code  int
+		reply string
+	}{
+		{nil, "method=Svc.Sum&params=[3,5]&id=1", http.StatusMethodNotAllowed, ""},
+		{[]jsonrpc1.ServerOption{jsonrpc1.ServerHTTPGet()}, "method=Svc.Sum&params=[3,5]&id=1", http.StatusOK, jRes},
+		{[]jsonrpc1.ServerOption{jsonrpc1.ServerHTTPGet()}, "method=Svc.Sum&params=WzMsNV0%3D&id=1", http.StatusOK, jRes},
+		{[]jsonrpc1.ServerOption{jsonrpc1.ServerHTTPGet()}, "method=Svc.Sum&params=WzMsNV0&id=1", http.StatusOK, jParse},
+		{[]jsonrpc1.ServerOption{jsonrpc1.ServerHTTPGet()}, "method=Svc.Sum&params=[3,5", http.StatusOK, jParse},
+		{[]jsonrpc1.ServerOption{jsonrpc1.ServerHTTPGet()}, "method=Svc.Sum&params=[3,5]", http.StatusNoContent, ""},
+		{[]jsonrpc1.ServerOption{jsonrpc1.ServerHTTPGet("Svc.Sum")}, "method=Svc.Sum&params=[3,5]&id=1", http.StatusOK, jRes},
+		{[]jsonrpc1.ServerOption{jsonrpc1.ServerHTTPGet("Svc.Sum")}, "method=Svc.Name&id=1", http.StatusMethodNotAllowed, ""},
+		{[]jsonrpc1.ServerOption{jsonrpc1.ServerHTTPGet(), jsonrpc1.ServerJSONRPC2()}, `method=Svc.Sum&params=[3,5]&id="a"`, http.StatusOK, jRes2},
+	}
+
+	for _, c := range cases {
+		ts := httptest.NewServer(jsonrpc1.NewServer(nil, c.opts...))
+		resp, err := http.Get(ts.URL + "?" + c.query)
+		if err != nil {
+			t.Fatalf("GET ?%s, err = %v", c.query, err)
+		}
+		if resp.StatusCode != c.code {
+			t.Errorf("GET ?%s, status = %v, want = %v", c.query, resp.StatusCode, c.code)
+		}
+		got, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			t.Errorf("ReadAll(), err = %v", err)
+		}
+		if c.reply == "" {
+			if len(got) != 0 {
+				t.Errorf("GET ?%s\nexp: %#q\ngot: %#q", c.query, c.reply, string(bytes.TrimRight(got, "\n")))
+			}
+		} else {
+			var jgot, jwant interface{}
+			if err := json.Unmarshal(got, &jgot); err != nil {
+				t.Errorf("GET ?%s, output err = %v\ngot: %#q", c.query, err, string(bytes.TrimRight(got, "\n")))
+			}
+			if err := json.Unmarshal([]byte(c.reply), &jwant); err != nil {
+				t.Errorf("GET ?%s, expect err = %v\nexp: %#q", c.query, err, c.reply)
+			}
+			if !reflect.DeepEqual(jgot, jwant) {
+				t.Errorf("GET ?%s\nexp: %#q\ngot: %#q", c.query, c.reply, string(bytes.TrimRight(got, "\n")))
+			}
+		}
+		ts.Close()
+	}
+}
+
 func TestHTTPClient(t *testing.T) {
 	ts := httptest.NewServer(jsonrpc1.HTTPHandler(nil))
 	// Don't close because of https://github.com/golang/go/issues/12262
@@ -0,0 +1,72 @@
+package jsonrpcf
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// IDGenerator returns ID for request number n sent by client (requests
+// are numbered from 0, including ones with ID set by ContextWithID). ID
+// must be a string or a number and must be unique among requests waiting
+// for reply.
+type IDGenerator func(n uint64) interface{}
+
+// WithIDGenerator sets generator of request IDs (SequenceIDs by default).
+func WithIDGenerator(gen IDGenerator) ClientOption {
+	return func(cfg *clientConfig) {
+		cfg.newID = gen
+	}
+}
+
+// SequenceIDs generates IDs 0, 1, 2, etc.
+func SequenceIDs(n uint64) interface{} {
+	return n
+}
+
+// PrefixedIDs returns IDGenerator which generates IDs prefix+"0",
+// prefix+"1", etc.
+func PrefixedIDs(prefix string) IDGenerator {
+	return func(n uint64) interface{} {
+		return prefix + strconv.FormatUint(n, 10)
+	}
+}
+
+// RandomIDs generates random UUIDs (version 4), like
+// "5f2b1c9e-0d4a-4c8e-9b7f-3a6d2e1f0c8b".
+func RandomIDs(uint64) interface{} {
+	var u [16]byte
+	if _, err := rand.Read(u[:]); err != nil {
+		panic(err)
+	}
+	u[6] = u[6]&0x0f | 0x40
+	u[8] = u[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", u[:4], u[4:6], u[6:8], u[8:10], u[10:])
+}
+
+// ContextWithID returns copy of ctx which makes client.CallContext() use
+// given request ID (a string or a number) instead of generated one.
+func ContextWithID(ctx context.Context, id interface{}) context.Context {
+	return context.WithValue(ctx, requestIDContextKey, id)
+}
+
+// canonicalID returns key used to find request by ID echoed by server,
+// or false if id isn't a string or a number.
+func canonicalID(id []byte) (string, bool) {
+	var v interface{}
+	dec := json.NewDecoder(bytes.NewReader(id))
+	dec.UseNumber()
+	if dec.Decode(&v) != nil {
+		return "", false
+	}
+	switch v := v.(type) {
+	case string:
+		return strconv.Quote(v), true
+	case json.Number:
+		return v.String(), true
+	}
+	return "", false
+}
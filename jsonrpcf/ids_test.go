@@ -0,0 +1,133 @@
+package jsonrpcf
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIDGenerators(t *testing.T) {
+	if id := SequenceIDs(7); id != uint64(7) {
+		t.Errorf("SequenceIDs(7) = %#v, want 7", id)
+	}
+	if id := PrefixedIDs("req-")(7); id != "req-7" {
+		t.Errorf("PrefixedIDs(req-)(7) = %#v, want req-7", id)
+	}
+	uuid := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	id1, id2 := RandomIDs(0), RandomIDs(0)
+	if s, ok := id1.(string); !ok || !uuid.MatchString(s) || id1 == id2 {
+		t.Errorf("RandomIDs() = %#v, %#v, want different UUIDs", id1, id2)
+	}
+}
+
+func TestClientIDs(t *testing.T) {
+	cli, srv := net.Pipe()
+	defer srv.Close()
+	client := NewClient(cli, WithIDGenerator(PrefixedIDs("req-")))
+	defer client.Close()
+
+	// Server echoes id as result, but doesn't reply to Svc.Wait.
+	go func() {
+		r := bufio.NewReader(srv)
+		for {
+			line, err := r.ReadBytes('\n')
+			if err != nil {
+				return
+			}
+			var reqs []struct {
+				Method string
+				ID     json.RawMessage
+			}
+			if json.Unmarshal(line, &reqs) != nil {
+				json.Unmarshal(append(append([]byte{'['}, line...), ']'), &reqs)
+			}
+			var replies []string
+			for _, req := range reqs {
+				if req.Method != "Svc.Wait" {
+					replies = append(replies, fmt.Sprintf(`{"id":%s,"result":%s,"error":null}`, req.ID, req.ID))
+				}
+			}
+			switch {
+			case line[0] == '[':
+				fmt.Fprintf(srv, "[%s]\n", strings.Join(replies, ","))
+			case len(replies) > 0:
+				fmt.Fprintf(srv, "%s\n", replies[0])
+			}
+		}
+	}()
+
+	var got interface{}
+	if err := client.Call("Svc.ID", nil, &got); err != nil || got != "req-0" {
+		t.Errorf("Call() = %#v, %v, want req-0, nil", got, err)
+	}
+	for _, id := range []interface{}{"custom", 42.0} {
+		ctx := ContextWithID(context.Background(), id)
+		if err := client.CallContext(ctx, "Svc.ID", nil, &got); err != nil || got != id {
+			t.Errorf("CallContext(%#v) = %#v, %v, want %#v, nil", id, got, err, id)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- client.CallContext(ContextWithID(ctx, "dup"), "Svc.Wait", nil, nil) }()
+	for client.codec.idsOf([]json.RawMessage{json.RawMessage(`"dup"`)}) == nil {
+		time.Sleep(time.Millisecond)
+	}
+	err := client.CallContext(ContextWithID(context.Background(), "dup"), "Svc.ID", nil, &got)
+	if err == nil || ServerError(err) == nil {
+		t.Errorf("CallContext() with duplicate id, err = %v", err)
+	}
+	cancel()
+	<-done
+	// ID of canceled call may be used again.
+	if err := client.CallContext(ContextWithID(context.Background(), "dup"), "Svc.ID", nil, &got); err != nil || got != "dup" {
+		t.Errorf("CallContext() with id of canceled call = %#v, %v, want dup, nil", got, err)
+	}
+	err = client.CallContext(ContextWithID(context.Background(), []int{1}), "Svc.ID", nil, &got)
+	if err == nil || ServerError(err) == nil {
+		t.Errorf("CallContext() with bad id, err = %v", err)
+	}
+
+	b := client.Batch()
+	call1 := b.Call("Svc.ID", nil, new(string))
+	call2 := b.Call("Svc.ID", nil, new(string))
+	// IDs 1-4 were used by calls with custom ID.
+	if err := b.Send(); err != nil || *call1.Reply.(*string) != "req-5" || *call2.Reply.(*string) != "req-6" {
+		t.Errorf("Batch.Send() = %v, %v, %v, want req-5, req-6", err, *call1.Reply.(*string), *call2.Reply.(*string))
+	}
+}
+
+func TestHTTPClientIDs(t *testing.T) {
+	ts := httptest.NewServer(NewServer(nil, ServerHTTPGet("Svc.Sum")))
+	defer ts.Close()
+
+	for _, opts := range [][]ClientOption{
+		{WithIDGenerator(RandomIDs)},
+		{WithIDGenerator(PrefixedIDs("req-")), WithHTTPGet("Svc.Sum")},
+	} {
+		client := NewHTTPClient(ts.URL, opts...)
+		var got int
+		if err := client.Call("Svc.Sum", [2]int{3, 5}, &got); err != nil || got != 8 {
+			t.Errorf("Call() = %v, %v, want 8, nil", got, err)
+		}
+		ctx := ContextWithID(context.Background(), "custom")
+		if err := client.CallContext(ctx, "Svc.Sum", [2]int{1, 2}, &got); err != nil || got != 3 {
+			t.Errorf("CallContext() = %v, %v, want 3, nil", got, err)
+		}
+		var got1, got2 int
+		b := client.Batch()
+		b.Call("Svc.Sum", [2]int{1, 1}, &got1)
+		b.Call("Svc.Sum", [2]int{2, 2}, &got2)
+		if err := b.Send(); err != nil || got1 != 2 || got2 != 4 {
+			t.Errorf("Batch.Send() = %v, %v, %v, want 2, 4, nil", got1, got2, err)
+		}
+		client.Close()
+	}
+}
@@ -0,0 +1,159 @@
+package jsonrpcf
+
+import (
+	"encoding/json"
+	"errors"
+	"net/rpc"
+)
+
+// callMethod is an internal rpc service method used by server to call
+// RPC methods through interceptors and funcs registered in Registry.
+const callMethod = "JSONRPC1.Call"
+
+// ServerCall describes call of RPC method for Interceptor.
+//
+// Request context (same as provided to RPC method, see WithContext) is
+// available using Context and can be replaced using SetContext before
+// calling next handler.
+type ServerCall struct {
+	Method string          // like "Svc.Method"
+	Params json.RawMessage // nil if request has no "params"
+	ID     json.RawMessage // nil for notification
+	Ctx
+}
+
+// ServerHandler executes call and returns its result or error.
+type ServerHandler func(call *ServerCall) (result interface{}, err error)
+
+// Interceptor is called by server for each call instead of RPC method
+// (including each call in batch request) and should call next to execute
+// it. Result and error returned by next are json.RawMessage and *Error
+// for net/rpc methods or values returned by func registered in Registry.
+//
+// Interceptor may return other result or error (like *Error) without
+// calling next. Result will be encoded using json.Marshal, error which is
+// not *Error (and doesn't wrap *Error) will be sent with code -32000.
+type Interceptor func(call *ServerCall, next ServerHandler) (result interface{}, err error)
+
+// ServerInterceptors adds interceptors to server. First interceptor is
+// called first and its next calls second interceptor, etc., and last
+// interceptor's next calls RPC method.
+func ServerInterceptors(interceptors ...Interceptor) ServerOption {
+	return func(cfg *serverConfig) {
+		cfg.interceptors = append(cfg.interceptors, interceptors...)
+	}
+}
+
+// CallArg is a param for internal RPC JSONRPC1.Call.
+type CallArg struct {
+	srv  *rpc.Server
+	cfg  *serverConfig
+	call *ServerCall
+}
+
+// CallReply is a reply of internal RPC JSONRPC1.Call. It keeps error as
+// is instead of returning it to net/rpc, which converts errors to string.
+type CallReply struct {
+	result interface{}
+	err    error
+}
+
+// Call is an internal RPC method used to call RPC methods through
+// interceptors and funcs registered in Registry.
+func (JSONRPC1) Call(arg CallArg, reply *CallReply) error {
+	if arg.call == nil { // called by client
+		return NewError(errMethod.Code, "rpc: can't find method "+callMethod)
+	}
+	reply.result, reply.err = arg.cfg.intercept(arg.srv, 0)(arg.call)
+	return nil
+}
+
+// encode returns result and error to be sent in reply.
+func (reply *CallReply) encode() (result, err interface{}) {
+	if reply.err == nil {
+		buf, e := json.Marshal(reply.result)
+		if e == nil {
+			return json.RawMessage(buf), nil
+		}
+		reply.err = NewError(errInternal.Code, e.Error())
+	}
+	var e *Error
+	if !errors.As(reply.err, &e) {
+		e = NewError(errServer.Code, reply.err.Error())
+	}
+	raw := json.RawMessage(e.Error())
+	return nil, &raw
+}
+
+// needCall returns true if server should call method using JSONRPC1.Call.
+func (cfg *serverConfig) needCall(method string) bool {
+	return method != batchMethod && (cfg.interceptors != nil || cfg.registry.lookup(method) != nil)
+}
+
+// intercept returns handler which calls interceptors starting from i-th
+// and then func registered in cfg.registry or RPC method registered in
+// srv.
+func (cfg *serverConfig) intercept(srv *rpc.Server, i int) ServerHandler {
+	if i == len(cfg.interceptors) {
+		return func(call *ServerCall) (interface{}, error) {
+			if f := cfg.registry.lookup(call.Method); f != nil {
+				return f.call(call)
+			}
+			codec := &callCodec{method: cfg.mapMethod(call.Method), call: call}
+			srv.ServeRequest(codec)
+			return codec.result, codec.err
+		}
+	}
+	next := cfg.intercept(srv, i+1)
+	return func(call *ServerCall) (interface{}, error) {
+		return cfg.interceptors[i](call, next)
+	}
+}
+
+// callCodec is a rpc.ServerCodec used to call RPC method with single
+// request and get its reply.
+type callCodec struct {
+	method string // net/rpc service method
+	call   *ServerCall
+	result json.RawMessage
+	err    error
+}
+
+func (c *callCodec) ReadRequestHeader(r *rpc.Request) error {
+	r.ServiceMethod = c.method
+	return nil
+}
+
+func (c *callCodec) ReadRequestBody(x interface{}) error {
+	if x == nil {
+		return nil
+	}
+	if x, ok := x.(WithContext); ok {
+		x.SetContext(c.call.Context())
+	}
+	return unmarshalParams(c.call.Params, x)
+}
+
+func (c *callCodec) WriteResponse(r *rpc.Response, x interface{}) error {
+	switch {
+	case r.Error == "":
+		buf, err := json.Marshal(x)
+		if err != nil {
+			c.err = NewError(errInternal.Code, err.Error())
+		}
+		c.result = buf
+	case r.Error[0] == '{' && r.Error[len(r.Error)-1] == '}':
+		e := &Error{}
+		if json.Unmarshal([]byte(r.Error), e) != nil {
+			e = newError(r.Error)
+		}
+		c.err = e
+	default:
+		c.err = newError(r.Error)
+	}
+	return nil
+}
+
+func (c *callCodec) Close() error {
+	return nil
+}
@@ -0,0 +1,173 @@
+package jsonrpcf
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/rpc"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestServerInterceptors(t *testing.T) {
+	var mu sync.Mutex
+	var log []string
+	logger := func(call *ServerCall, next ServerHandler) (interface{}, error) {
+		result, err := next(call)
+		res, _ := json.Marshal(result)
+		mu.Lock()
+		log = append(log, fmt.Sprintf("%s %s %s: %s %v", call.ID, call.Method, call.Params, res, err))
+		mu.Unlock()
+		return result, err
+	}
+	deny := func(call *ServerCall, next ServerHandler) (interface{}, error) {
+		if call.Context() == nil {
+			t.Errorf("%s: no context", call.Method)
+		}
+		if call.Method == "Svc.Err" {
+			return nil, NewError(-1, "denied")
+		}
+		return next(call)
+	}
+	opts := []ServerOption{ServerInterceptors(logger), ServerInterceptors(deny)}
+
+	cases := []struct {
+		req   string
+		reply string
+		log   []string
+	}{
+		{
+			`{"id":1,"method":"Svc.Sum","params":[3,5]}`,
+			`{"id":1,"result":8,"error":null}`,
+			[]string{`1 Svc.Sum [3,5]: 8 <nil>`},
+		},
+		{
+			`{"id":"a","method":"Svc.Err","params":{}}`,
+			`{"id":"a","error":{"code":-1,"message":"denied"}}`,
+			[]string{`"a" Svc.Err {}: null {"code":-1,"message":"denied"}`},
+		},
+		{
+			`{"id":2,"method":"Svc.Err3","params":{}}`,
+			`{"id":2,"error":{"code":42,"message":"some issue","data":{"one":1,"two":2}}}`,
+			[]string{`2 Svc.Err3 {}: null {"code":42,"message":"some issue","data":{"one":1,"two":2}}`},
+		},
+		{
+			`{"id":3,"method":"Svc.Bad","params":[]}`,
+			`{"id":3,"error":{"code":-32601,"message":"rpc: can't find method Svc.Bad"}}`,
+			[]string{`3 Svc.Bad []: null {"code":-32601,"message":"rpc: can't find method Svc.Bad"}`},
+		},
+		{
+			`{"id":4,"method":"Svc.Sum","params":{}}`,
+			`{"id":4,"error":{"code":-32602,"message":"json: cannot unmarshal object into Go value of type [2]int"}}`,
+			[]string{`4 Svc.Sum {}: null {"code":-32602,"message":"json: cannot unmarshal object into Go value of type [2]int"}`},
+		},
+		{
+			`{"id":5,"method":"JSONRPC1.Call","params":{}}`,
+			`{"id":5,"error":{"code":-32601,"message":"rpc: can't find method JSONRPC1.Call"}}`,
+			[]string{`5 JSONRPC1.Call {}: null {"code":-32601,"message":"rpc: can't find method JSONRPC1.Call"}`},
+		},
+		{
+			`[{"id":6,"method":"Svc.Sum","params":[1,2]},{"id":7,"method":"Svc.Err","params":{}}]`,
+			`[{"id":6,"result":3,"error":null},{"id":7,"error":{"code":-1,"message":"denied"}}]`,
+			[]string{`6 Svc.Sum [1,2]: 3 <nil>`, `7 Svc.Err {}: null {"code":-1,"message":"denied"}`},
+		},
+	}
+
+	transports, closeTransports := serverTransports(opts...)
+	defer closeTransports()
+	for name, roundTrip := range transports {
+		for _, c := range cases {
+			log = nil
+			reply := roundTrip(c.req)
+			if !batchEqual(reply, c.reply) {
+				t.Errorf("%s: %s:\nexp: %s\ngot: %s", name, c.req, c.reply, reply)
+			}
+			mu.Lock()
+			sort.Strings(log)
+			if !reflect.DeepEqual(log, c.log) {
+				t.Errorf("%s: %s:\nexp log: %q\ngot log: %q", name, c.req, c.log, log)
+			}
+			mu.Unlock()
+		}
+	}
+}
+
+// serverTransports returns funcs which send raw request to server with
+// given options using ServeConn and HTTPHandler and return raw reply.
+func serverTransports(opts ...ServerOption) (map[string]func(req string) string, func()) {
+	return rpcServerTransports(rpc.DefaultServer, opts...)
+}
+
+// rpcServerTransports is like serverTransports, but server serves methods
+// of srv instead of rpc.DefaultServer.
+func rpcServerTransports(srv *rpc.Server, opts ...ServerOption) (map[string]func(req string) string, func()) {
+	ts := httptest.NewServer(NewServer(srv, opts...))
+	return map[string]func(req string) string{
+		"ServeConn": func(req string) string {
+			cli, conn := net.Pipe()
+			defer cli.Close()
+			go NewServer(srv, opts...).ServeConn(conn)
+			go cli.Write([]byte(req))
+			reply, _ := bufio.NewReader(cli).ReadString('\n')
+			return reply
+		},
+		"HTTPHandler": func(req string) string {
+			r, _ := http.NewRequest("POST", ts.URL, strings.NewReader(req))
+			r.Header.Set("Content-Type", contentType)
+			r.Header.Set("Accept", contentType)
+			resp, err := http.DefaultClient.Do(r)
+			if err != nil {
+				return err.Error()
+			}
+			defer resp.Body.Close()
+			reply, _ := ioutil.ReadAll(resp.Body)
+			return string(reply)
+		},
+	}, ts.Close
+}
+
+// batchEqual compares JSON replies ignoring order of replies in batch.
+func batchEqual(reply, want string) bool {
+	var jgot, jwant interface{}
+	json.Unmarshal([]byte(reply), &jgot)
+	json.Unmarshal([]byte(want), &jwant)
+	sortBatch(jgot)
+	sortBatch(jwant)
+	return reflect.DeepEqual(jgot, jwant)
+}
+
+type PanicSvc struct{}
+
+func (PanicSvc) Panic(struct{}, *struct{}) error {
+	panic("oops")
+}
+
+func TestServerInterceptorsRecover(t *testing.T) {
+	srv := rpc.NewServer()
+	srv.Register(PanicSvc{})
+	recoverer := func(call *ServerCall, next ServerHandler) (result interface{}, err error) {
+		defer func() {
+			if e := recover(); e != nil {
+				result, err = nil, NewError(errInternal.Code, fmt.Sprint(e))
+			}
+		}()
+		return next(call)
+	}
+	cli, conn := net.Pipe()
+	defer cli.Close()
+	go NewServer(srv, ServerInterceptors(recoverer)).ServeConn(conn)
+	client := NewClient(cli)
+	for i := 0; i < 2; i++ {
+		err := client.Call("PanicSvc.Panic", struct{}{}, nil)
+		if e := ServerError(err); e == nil || e.Code != errInternal.Code || e.Message != "oops" {
+			t.Errorf("Call() = %v, want oops", err)
+		}
+	}
+}
@@ -0,0 +1,234 @@
+package jsonrpcf
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/rpc"
+)
+
+// ErrResponseTooLarge is wrapped in *TransportError returned by client
+// when reply is larger than limit set by WithMaxResponseSize.
+var ErrResponseTooLarge = errors.New("response too large")
+
+var (
+	errTooLarge = NewError(errRequest.Code, "Request too large")
+	errTooLong  = NewError(errRequest.Code, "Batch too long")
+)
+
+// ServerMaxRequestSize limits size of single request (including batch
+// request) to max bytes (no limit by default). Server replies to larger
+// request with error -32600 and closes connection without reading rest of
+// request.
+func ServerMaxRequestSize(max int64) ServerOption {
+	return func(cfg *serverConfig) {
+		cfg.maxRequestSize = max
+	}
+}
+
+// ServerMaxBatchLength limits amount of requests in batch request to max
+// (no limit by default). Server replies to longer batch request with
+// error -32600 and closes connection.
+func ServerMaxBatchLength(max int) ServerOption {
+	return func(cfg *serverConfig) {
+		cfg.maxBatchLength = max
+	}
+}
+
+// WithMaxResponseSize limits size of single reply (including reply to
+// batch request) to max bytes (no limit by default). Larger reply is
+// skipped while reading and related call (or all calls of related batch)
+// fails with *TransportError wrapping ErrResponseTooLarge.
+func WithMaxResponseSize(max int64) ClientOption {
+	return func(cfg *clientConfig) {
+		cfg.maxResponseSize = max
+	}
+}
+
+// jsonReader reads JSON values from stream without keeping more than max
+// bytes of each value in memory.
+type jsonReader struct {
+	r    *bufio.Reader
+	max  int64
+	stop bool // stop reading too large value instead of skipping it
+}
+
+func newJSONReader(r io.Reader, max int64) *jsonReader {
+	return &jsonReader{r: bufio.NewReader(r), max: max}
+}
+
+func isSpace(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\r' || b == '\n'
+}
+
+// next reads next JSON value. If value is larger than max then it's
+// skipped (or, if jr.stop is set, reading stops in the middle of value)
+// and tooLarge is true; in this case id contains "id" member of value (or
+// of its first element if value is an array) or nil if there is no such
+// member, it's larger than max or wasn't read yet.
+//
+// Returned value is not validated and may contain bad JSON. It returns
+// io.EOF if there are no more values or io.ErrUnexpectedEOF if stream
+// ends in the middle of value.
+func (jr *jsonReader) next() (raw, id []byte, tooLarge bool, err error) {
+	b, err := jr.r.ReadByte()
+	for err == nil && isSpace(b) {
+		b, err = jr.r.ReadByte()
+	}
+	if err != nil {
+		return nil, nil, false, err
+	}
+
+	idDepth := 1
+	if b == '[' {
+		idDepth = 2
+	}
+	var (
+		n          int64
+		depth      int  // amount of opened objects and arrays
+		isObject   bool // container at idDepth is an object
+		inStr, esc bool
+		expectKey  bool   // next string at idDepth is a key
+		inKey      bool   // reading key at idDepth
+		key        []byte // key at idDepth, up to 3 bytes
+		isID       bool   // "id" key was read, waiting for its value
+		inID       bool   // reading value of "id"
+		haveID     bool
+	)
+	for {
+		if n++; n > jr.max {
+			if jr.stop {
+				if inID {
+					id = nil
+				}
+				return nil, id, true, nil
+			}
+			tooLarge, raw = true, nil
+		} else {
+			raw = append(raw, b)
+		}
+		if inID && int64(len(id)) > jr.max {
+			inID, id = false, nil
+		}
+
+		switch {
+		case inStr:
+			switch {
+			case esc:
+				esc = false
+			case b == '\\':
+				esc = true
+			case b == '"':
+				inStr = false
+			}
+			switch {
+			case inKey && !inStr:
+				inKey = false
+				isID = !haveID && string(key) == "id"
+			case inKey && len(key) < 3:
+				key = append(key, b)
+			}
+			if inID {
+				id = append(id, b)
+			}
+		case b == '"':
+			inStr = true
+			if expectKey {
+				expectKey, inKey, key = false, true, key[:0]
+			}
+			if inID {
+				id = append(id, b)
+			}
+		case b == '{' || b == '[':
+			if inID { // id can't be object or array
+				inID, id = false, nil
+			}
+			if depth++; depth == idDepth {
+				isObject = b == '{'
+			}
+			expectKey = isObject && depth == idDepth
+		case b == '}' || b == ']':
+			if depth == idDepth {
+				inID, expectKey = false, false
+			}
+			if depth > 0 {
+				depth--
+			}
+		case b == ',':
+			if depth == idDepth {
+				inID = false
+				expectKey = isObject
+			}
+		case b == ':':
+			if isID && depth == idDepth {
+				isID, inID, haveID = false, true, true
+			}
+		case depth == 0: // scalar value: read until delimiter
+			for {
+				if b, err = jr.r.ReadByte(); err != nil {
+					if err == io.EOF {
+						err = nil
+					}
+					return raw, nil, tooLarge, err
+				}
+				if isSpace(b) || b == '{' || b == '[' || b == '"' || b == ',' || b == ']' || b == '}' {
+					jr.r.UnreadByte()
+					return raw, nil, tooLarge, nil
+				}
+				if n++; n > jr.max {
+					if jr.stop {
+						return nil, nil, true, nil
+					}
+					tooLarge, raw = true, nil
+				} else {
+					raw = append(raw, b)
+				}
+			}
+		default:
+			if inID && !isSpace(b) {
+				id = append(id, b)
+			}
+		}
+
+		if depth == 0 && !inStr {
+			if !tooLarge {
+				id = nil
+			}
+			return raw, id, tooLarge, nil
+		}
+		if b, err = jr.r.ReadByte(); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, nil, false, err
+		}
+	}
+}
+
+// tooLarge fills r to return ErrResponseTooLarge for call related to
+// reply with given id. It returns error if there is no such call.
+func (c *clientCodec) tooLarge(r *rpc.Response, id []byte) error {
+	err := &TransportError{Err: ErrResponseTooLarge}
+	if _, ok := canonicalID(id); !ok {
+		// Reply can't be related to any call, so fail all calls.
+		return err
+	}
+	wireID := json.RawMessage(id)
+	c.mutex.Lock()
+	n, ok := c.lookup(&wireID)
+	c.mutex.Unlock()
+	if !ok { // reply to unknown request
+		r.Error = ""
+		r.Seq = seqNotify
+		return nil
+	}
+	return c.failRequests(r, []uint64{n}, err)
+}
+
+// batchTooLong returns true if raw is JSON array with more than max
+// elements.
+func batchTooLong(raw json.RawMessage, max int) bool {
+	var reqs []json.RawMessage
+	return json.Unmarshal(raw, &reqs) == nil && len(reqs) > max
+}
@@ -0,0 +1,169 @@
+package jsonrpcf
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestJSONReader(t *testing.T) {
+	type value struct {
+		raw      string
+		id       string
+		tooLarge bool
+	}
+	cases := []struct {
+		in   string
+		want []value
+		err  error
+	}{
+		{``, nil, io.EOF},
+		{" \n", nil, io.EOF},
+		{`{"id":1} [2] "s" 3 null{}`, []value{
+			{`{"id":1}`, "", false},
+			{`[2]`, "", false},
+			{`"s"`, "", false},
+			{`3`, "", false},
+			{`null`, "", false},
+			{`{}`, "", false},
+		}, io.EOF},
+		{`{"result":"0123456789abcdef","error":null,"id":42}{"id":1}`, []value{
+			{``, "42", true},
+			{`{"id":1}`, "", false},
+		}, io.EOF},
+		{`{"id" : "a,\"b}" ,"result":{"id":7}, "x":"0123456789abcdef"}`, []value{
+			{``, `"a,\"b}"`, true},
+		}, io.EOF},
+		{`{"result":{"id":7,"x":"0123456789abcdef"},"idx":1,"id":null}`, []value{
+			{``, "null", true},
+		}, io.EOF},
+		{`[{"result":"0123456789abcdef","id":3},{"id":4}]`, []value{
+			{``, "3", true},
+		}, io.EOF},
+		{`{"result":"0123456789abcdef"}`, []value{
+			{``, "", true},
+		}, io.EOF},
+		{`{"id":1`, nil, io.ErrUnexpectedEOF},
+	}
+	for _, c := range cases {
+		jr := &jsonReader{r: bufio.NewReader(strings.NewReader(c.in)), max: 24}
+		for _, want := range c.want {
+			raw, id, tooLarge, err := jr.next()
+			if err != nil || string(raw) != want.raw || string(id) != want.id || tooLarge != want.tooLarge {
+				t.Errorf("%#q: next() = %#q, %#q, %v, %v, want %#q, %#q, %v", c.in, raw, id, tooLarge, err, want.raw, want.id, want.tooLarge)
+			}
+		}
+		if _, _, _, err := jr.next(); err != c.err {
+			t.Errorf("%#q: last next(), err = %v, want %v", c.in, err, c.err)
+		}
+	}
+}
+
+func TestJSONReaderStop(t *testing.T) {
+	cases := []struct {
+		in string
+		id string
+	}{
+		{`{"id":1,"result":"0123456789abcdef"}`, "1"},
+		{`{"result":"0123456789abcdef","id":1}`, ""},
+		{`{"id":"0123456789abcdef0123456789"}`, ""},
+		{`"0123456789abcdef0123456789"`, ""},
+	}
+	for _, c := range cases {
+		jr := &jsonReader{r: bufio.NewReader(strings.NewReader(c.in + ` {}`)), max: 24, stop: true}
+		raw, id, tooLarge, err := jr.next()
+		if err != nil || raw != nil || string(id) != c.id || !tooLarge {
+			t.Errorf("%#q: next() = %#q, %#q, %v, %v, want nil, %#q, true, nil", c.in, raw, id, tooLarge, err, c.id)
+		}
+	}
+}
+
+func TestJSONReaderBoundedMemory(t *testing.T) {
+	const size = 1 << 20
+	cases := []string{
+		strings.Repeat("[", size) + strings.Repeat("]", size),
+		strings.Repeat(`{"a":`, size) + "1" + strings.Repeat("}", size),
+		`{"id":"` + strings.Repeat("x", size) + `"}`,
+	}
+	for _, in := range cases {
+		jr := newJSONReader(strings.NewReader(in), 24)
+		var before, after runtime.MemStats
+		runtime.ReadMemStats(&before)
+		raw, id, tooLarge, err := jr.next()
+		runtime.ReadMemStats(&after)
+		if err != nil || raw != nil || id != nil || !tooLarge {
+			t.Errorf("%.16s...: next() = %#q, %#q, %v, %v, want nil, nil, true, nil", in, raw, id, tooLarge, err)
+		}
+		if alloc := after.TotalAlloc - before.TotalAlloc; alloc > size/16 {
+			t.Errorf("%.16s...: next() allocated %d bytes", in, alloc)
+		}
+	}
+}
+
+func TestServerLimits(t *testing.T) {
+	cases := []struct {
+		req   string
+		reply string
+	}{
+		{`{"id":1,"method":"Svc.Sum","params":[3,5]}`, `{"id":1,"result":8,"error":null}`},
+		{`{"id":2,"method":"Svc.SumAll","params":[1,2,3,4,5,6,7,8,9,10,11,12,13,14,15,16,17,18,19,20,21,22,23,24]}`,
+			`{"id":2,"error":{"code":-32600,"message":"Request too large"}}`},
+		{`[{"id":3,"method":"Svc.Sum","params":[3,5]},{"id":4,"method":"Svc.Sum","params":[3,5]}]`,
+			`{"id":null,"error":{"code":-32600,"message":"Batch too long"}}`},
+		// Server doesn't wait for end of too large request.
+		{`{"id":5,"method":"Svc.SumAll","params":[` + strings.Repeat("1,", 1<<20),
+			`{"id":5,"error":{"code":-32600,"message":"Request too large"}}`},
+	}
+	for _, c := range cases {
+		cli, srv := net.Pipe()
+		cli.SetReadDeadline(time.Now().Add(time.Second))
+		go NewServer(nil, ServerMaxRequestSize(100), ServerMaxBatchLength(1)).ServeConn(srv)
+		go cli.Write([]byte(c.req))
+		reply, err := bufio.NewReader(cli).ReadString('\n')
+		if err != nil {
+			t.Errorf("%s: read reply, err = %v", c.req, err)
+		}
+		if !jsonEqual(reply, c.reply) {
+			t.Errorf("%s:\nexp: %s\ngot: %s", c.req, c.reply, reply)
+		}
+		cli.Close()
+	}
+}
+
+func TestClientMaxResponseSize(t *testing.T) {
+	ln, drop := tcpServer(t)
+	defer ln.Close()
+	defer drop()
+	tcpClient, err := Dial("tcp", ln.Addr().String(), WithMaxResponseSize(100))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tcpClient.Close()
+	ts := httptest.NewServer(HTTPHandler(nil))
+	defer ts.Close()
+	httpClient := NewHTTPClient(ts.URL, WithMaxResponseSize(100))
+	defer httpClient.Close()
+
+	for _, client := range []*Client{tcpClient, httpClient} {
+		var name NameRes
+		var terr *TransportError
+		err := client.Call("Svc.Name", NameArg{strings.Repeat("x", 100), "Last"}, &name)
+		if !errors.As(err, &terr) || !errors.Is(err, ErrResponseTooLarge) {
+			t.Errorf("Call() with large reply, err = %v", err)
+		}
+		if err := client.Call("Svc.Name", NameArg{"First", "Last"}, &name); err != nil || name.Name != "First Last" {
+			t.Errorf("Call() = %v, %v, want First Last, nil", name, err)
+		}
+		b := client.Batch()
+		call := b.Call("Svc.Name", NameArg{strings.Repeat("x", 100), "Last"}, &name)
+		if err := b.Send(); !errors.Is(err, ErrResponseTooLarge) || call.Error == nil {
+			t.Errorf("Batch.Send() with large reply, err = %v", err)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package jsonrpcf
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+)
+
+// LongPoll invokes the named function, waits for it to complete, and
+// returns its error status. It's intended for calls which may wait for
+// reply for minutes, like getblocktemplate with "longpollid".
+//
+// Unlike CallContext, LongPoll ignores timeouts set by WithTimeout and
+// WithMethodTimeout and RetryPolicy set by WithRetry: use ctx to cancel
+// it. HTTP client sends long poll using dedicated HTTP request whose reply
+// is read separately from other calls, so long poll doesn't delay them.
+//
+// Other clients send long poll as a usual call over their connection, so
+// server which handles requests sequentially will delay other calls until
+// long poll completes.
+func (c *Client) LongPoll(ctx context.Context, serviceMethod string, args interface{}, reply interface{}) error {
+	conn, ok := c.codec.c.(*httpClientConn)
+	if !ok {
+		return c.call(ctx, serviceMethod, args, reply)
+	}
+	poll := newHTTPClient(conn.url, conn.root, conn.doer, conn.cfg, newHTTPSlots(conn.cfg))
+	defer poll.Close()
+	return poll.call(ctx, serviceMethod, args, reply)
+}
+
+// LongPollLoop calls serviceMethod using LongPoll until ctx is done,
+// passing each result to f. It calls serviceMethod with params(id), where
+// id is "longpollid" member of previous result (empty for first call):
+//
+//	err := client.LongPollLoop(ctx, "getblocktemplate", func(id string) interface{} {
+//		req := map[string]interface{}{"rules": []string{"segwit"}}
+//		if id != "" {
+//			req["longpollid"] = id
+//		}
+//		return []interface{}{req}
+//	}, func(template json.RawMessage) error {
+//		...
+//	})
+//
+// LongPollLoop returns ctx.Err() when ctx is done, or first error
+// returned by call or f. It also fails if result has no "longpollid".
+func (c *Client) LongPollLoop(ctx context.Context, serviceMethod string, params func(longPollID string) interface{}, f func(result json.RawMessage) error) error {
+	var id string
+	for {
+		var result json.RawMessage
+		if err := c.LongPoll(ctx, serviceMethod, params(id), &result); err != nil {
+			return err
+		}
+		if err := f(result); err != nil {
+			return err
+		}
+		var poll struct {
+			LongPollID string `json:"longpollid"`
+		}
+		if err := json.Unmarshal(result, &poll); err != nil || poll.LongPollID == "" {
+			return errors.New("no longpollid in result of " + serviceMethod)
+		}
+		id = poll.LongPollID
+	}
+}
@@ -0,0 +1,172 @@
+package jsonrpcf
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// chainServer serves rpc.DefaultServer over HTTP and getblocktemplate
+// which waits for new block if called with longpollid of current one.
+type chainServer struct {
+	mu      sync.Mutex
+	height  int
+	changed chan struct{}
+}
+
+func (s *chainServer) mine() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.height++
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+func (s *chainServer) handler() http.Handler {
+	h := HTTPHandler(nil)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		var req struct {
+			Method string
+			Params []struct {
+				LongPollID string
+			}
+			ID *uint64
+		}
+		if json.Unmarshal(body, &req) != nil || req.Method != "getblocktemplate" {
+			r.Body = ioutil.NopCloser(bytes.NewReader(body))
+			h.ServeHTTP(w, r)
+			return
+		}
+		s.mu.Lock()
+		height, changed := s.height, s.changed
+		s.mu.Unlock()
+		if len(req.Params) > 0 && req.Params[0].LongPollID == fmt.Sprint(height) {
+			select {
+			case <-changed:
+			case <-r.Context().Done():
+				return
+			}
+			s.mu.Lock()
+			height = s.height
+			s.mu.Unlock()
+		}
+		w.Header().Set("Content-Type", contentType)
+		fmt.Fprintf(w, `{"id":%d,"result":{"height":%d,"longpollid":"%d"},"error":null}`, *req.ID, height, height)
+	})
+}
+
+func TestLongPoll(t *testing.T) {
+	chain := &chainServer{height: 1, changed: make(chan struct{})}
+	ts := httptest.NewServer(chain.handler())
+	defer ts.Close()
+	client := NewHTTPClient(ts.URL, WithTimeout(50*time.Millisecond))
+	defer client.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ids := make(chan string, 16)
+	heights := make(chan int, 16)
+	done := make(chan error, 1)
+	go func() {
+		done <- client.LongPollLoop(ctx, "getblocktemplate", func(id string) interface{} {
+			ids <- id
+			return []interface{}{map[string]string{"longpollid": id}}
+		}, func(result json.RawMessage) error {
+			var tmpl struct{ Height int }
+			if err := json.Unmarshal(result, &tmpl); err != nil {
+				return err
+			}
+			heights <- tmpl.Height
+			return nil
+		})
+	}()
+
+	if id, height := <-ids, <-heights; id != "" || height != 1 {
+		t.Errorf("first poll = %q, %d, want \"\", 1", id, height)
+	}
+	if id := <-ids; id != "1" {
+		t.Errorf("second poll id = %q, want 1", id)
+	}
+
+	// Long poll doesn't delay other calls and isn't limited by timeout.
+	for i := 0; i < 3; i++ {
+		var got int
+		if err := client.Call("Svc.Sum", [2]int{3, 5}, &got); err != nil || got != 8 {
+			t.Errorf("Call() while polling = %v, %v, want 8, nil", got, err)
+		}
+		time.Sleep(30 * time.Millisecond)
+	}
+	select {
+	case height := <-heights:
+		t.Fatalf("poll returned %d before new block", height)
+	default:
+	}
+
+	chain.mine()
+	if height := <-heights; height != 2 {
+		t.Errorf("poll after new block = %d, want 2", height)
+	}
+	if id := <-ids; id != "2" {
+		t.Errorf("third poll id = %q, want 2", id)
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != context.Canceled {
+			t.Errorf("LongPollLoop(), err = %v, want %v", err, context.Canceled)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("LongPollLoop() not canceled")
+	}
+}
+
+func TestLongPollConn(t *testing.T) {
+	cli, srv := net.Pipe()
+	defer srv.Close()
+	client := NewClient(cli, WithTimeout(10*time.Millisecond))
+	defer client.Close()
+	go func() {
+		buf := bufio.NewReader(srv)
+		buf.ReadString('\n')
+		time.Sleep(50 * time.Millisecond)
+		srv.Write([]byte(`{"id":0,"result":{"height":1,"longpollid":"1"},"error":null}` + "\n"))
+	}()
+
+	// Long poll is sent as usual call which ignores client's timeout.
+	var tmpl struct{ Height int }
+	if err := client.LongPoll(context.Background(), "getblocktemplate", nil, &tmpl); err != nil || tmpl.Height != 1 {
+		t.Errorf("LongPoll() = %+v, %v, want height 1", tmpl, err)
+	}
+}
+
+func TestLongPollLoopNoID(t *testing.T) {
+	ts := httptest.NewServer(HTTPHandler(nil))
+	defer ts.Close()
+	client := NewHTTPClient(ts.URL)
+	defer client.Close()
+
+	calls := 0
+	err := client.LongPollLoop(context.Background(), "Svc.Sum", func(string) interface{} {
+		return [2]int{3, 5}
+	}, func(result json.RawMessage) error {
+		calls++
+		return nil
+	})
+	if want := "no longpollid in result of Svc.Sum"; err == nil || err.Error() != want {
+		t.Errorf("LongPollLoop(), err = %v, want %s", err, want)
+	}
+	if calls != 1 {
+		t.Errorf("f called %d times, want 1", calls)
+	}
+}
@@ -0,0 +1,75 @@
+package jsonrpcf
+
+import "strings"
+
+// ServerMethodAlias makes server call net/rpc method (like
+// "Node.GetBlockCount") when client requests alias (like
+// "getblockcount"). Aliases have priority over other mappings (see
+// ServerMethodMapper) which aren't applied to method.
+func ServerMethodAlias(alias, method string) ServerOption {
+	return func(cfg *serverConfig) {
+		if cfg.aliases == nil {
+			cfg.aliases = make(map[string]string)
+		}
+		cfg.aliases[alias] = method
+	}
+}
+
+// ServerMethodMapper adds mapping of method names requested by client to
+// net/rpc service methods. Mappings are applied in order of options.
+//
+// Mappings don't apply to funcs registered in Registry and to method
+// names seen by interceptors.
+func ServerMethodMapper(f func(method string) string) ServerOption {
+	return func(cfg *serverConfig) {
+		cfg.mappers = append(cfg.mappers, f)
+	}
+}
+
+// ServerDefaultService adds mapping (see ServerMethodMapper) of method
+// names without '.' (like "getblockcount") to methods of given net/rpc
+// service (like "Node.getblockcount"). Use it with ServerCamelCase to get
+// name of exported method.
+func ServerDefaultService(service string) ServerOption {
+	return ServerMethodMapper(func(method string) string {
+		if strings.Contains(method, ".") {
+			return method
+		}
+		return service + "." + method
+	})
+}
+
+// ServerCamelCase adds mapping (see ServerMethodMapper) of lowercase and
+// snake_case names of service and method to CamelCase names of exported
+// Go types and methods: "mining.subscribe" to "Mining.Subscribe",
+// "mining.set_difficulty" to "Mining.SetDifficulty", "z_getbalance" to
+// "ZGetbalance".
+func ServerCamelCase() ServerOption {
+	return ServerMethodMapper(camelCase)
+}
+
+func camelCase(method string) string {
+	parts := strings.Split(method, ".")
+	for i, part := range parts {
+		words := strings.Split(part, "_")
+		for j, word := range words {
+			if word != "" {
+				words[j] = strings.ToUpper(word[:1]) + word[1:]
+			}
+		}
+		parts[i] = strings.Join(words, "")
+	}
+	return strings.Join(parts, ".")
+}
+
+// mapMethod returns net/rpc service method for method requested by
+// client.
+func (cfg *serverConfig) mapMethod(method string) string {
+	if m, ok := cfg.aliases[method]; ok {
+		return m
+	}
+	for _, f := range cfg.mappers {
+		method = f(method)
+	}
+	return method
+}
@@ -0,0 +1,126 @@
+package jsonrpcf
+
+import (
+	"net/rpc"
+	"sync"
+	"testing"
+)
+
+// Node is an RPC service for testing flat method names.
+type Node struct{}
+
+func (*Node) Getblockcount(_ []int, res *int) error {
+	*res = 100
+	return nil
+}
+
+func (*Node) ZGetbalance(_ []int, res *float64) error {
+	*res = 1.5
+	return nil
+}
+
+func (*Node) Info(_ []int, res *string) error {
+	*res = "info"
+	return nil
+}
+
+// Mining is an RPC service for testing Stratum method names.
+type Mining struct{}
+
+func (*Mining) Subscribe(_ []int, res *string) error {
+	*res = "sub"
+	return nil
+}
+
+func (*Mining) SetDifficulty(diff [1]float64, res *float64) error {
+	*res = diff[0]
+	return nil
+}
+
+func (*Mining) Auth(_ []string, res *bool) error {
+	*res = true
+	return nil
+}
+
+func TestCamelCase(t *testing.T) {
+	cases := map[string]string{
+		"getblockcount":         "Getblockcount",
+		"z_getbalance":          "ZGetbalance",
+		"mining.subscribe":      "Mining.Subscribe",
+		"mining.set_difficulty": "Mining.SetDifficulty",
+		"Node.Info":             "Node.Info",
+		"a__b_":                 "AB",
+		"":                      "",
+	}
+	for in, want := range cases {
+		if got := camelCase(in); got != want {
+			t.Errorf("camelCase(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestServerMethodMapping(t *testing.T) {
+	srv := rpc.NewServer()
+	srv.Register(&Node{})
+	srv.Register(&Mining{})
+	reg := NewRegistry()
+	reg.Register("getblockhash", func(height int) (string, error) {
+		return "hash", nil
+	})
+	var mu sync.Mutex
+	var methods []string
+	opts := []ServerOption{
+		ServerMethodAlias("getinfo", "Node.Info"),
+		ServerMethodAlias("mining.authorize", "Mining.Auth"),
+		ServerDefaultService("Node"),
+		ServerCamelCase(),
+		ServerRegistry(reg),
+	}
+
+	cases := []struct {
+		req   string
+		reply string
+	}{
+		{`{"id":1,"method":"getblockcount","params":[]}`, `{"id":1,"result":100,"error":null}`},
+		{`{"id":1,"method":"z_getbalance","params":[]}`, `{"id":1,"result":1.5,"error":null}`},
+		{`{"id":1,"method":"getinfo","params":[]}`, `{"id":1,"result":"info","error":null}`},
+		{`{"id":1,"method":"Node.Getblockcount","params":[]}`, `{"id":1,"result":100,"error":null}`},
+		{`{"id":1,"method":"mining.subscribe","params":[]}`, `{"id":1,"result":"sub","error":null}`},
+		{`{"id":1,"method":"mining.set_difficulty","params":[2]}`, `{"id":1,"result":2,"error":null}`},
+		{`{"id":1,"method":"mining.authorize","params":["user","pass"]}`, `{"id":1,"result":true,"error":null}`},
+		{`{"id":1,"method":"getblockhash","params":[1]}`, `{"id":1,"result":"hash","error":null}`},
+		{`{"id":1,"method":"nosuch","params":[]}`, `{"id":1,"error":{"code":-32601,"message":"rpc: can't find method Node.Nosuch"}}`},
+		{
+			`[{"id":1,"method":"getblockcount","params":[]},{"id":2,"method":"mining.subscribe","params":[]}]`,
+			`[{"id":1,"result":100,"error":null},{"id":2,"result":"sub","error":null}]`,
+		},
+	}
+	logger := ServerInterceptors(func(call *ServerCall, next ServerHandler) (interface{}, error) {
+		mu.Lock()
+		methods = append(methods, call.Method)
+		mu.Unlock()
+		return next(call)
+	})
+	for _, opts := range [][]ServerOption{opts, append(opts, logger)} {
+		transports, closeTransports := rpcServerTransports(srv, opts...)
+		for name, roundTrip := range transports {
+			for _, c := range cases {
+				if got := roundTrip(c.req); !batchEqual(got, c.reply) {
+					t.Errorf("%s: %s:\nexp: %s\ngot: %s", name, c.req, c.reply, got)
+				}
+			}
+		}
+		closeTransports()
+	}
+
+	// Interceptors see requested method names.
+	mu.Lock()
+	methods = methods[:0]
+	mu.Unlock()
+	transports, closeTransports := rpcServerTransports(srv, append(opts, logger)...)
+	defer closeTransports()
+	transports["ServeConn"](`{"id":1,"method":"mining.authorize","params":[]}`)
+	if len(methods) != 1 || methods[0] != "mining.authorize" {
+		t.Errorf("interceptor got methods %q, want [mining.authorize]", methods)
+	}
+}
@@ -0,0 +1,199 @@
+package jsonrpcf
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// posField is a struct field with `jsonrpc:"pos=N"` tag.
+type posField struct {
+	index    []int
+	name     string          // name in JSON object
+	optional bool            // may be missing
+	def      json.RawMessage // default value, if any
+}
+
+// posFields caches fields with `jsonrpc` tag (or error) for struct types.
+var posFields sync.Map // map[reflect.Type]posFieldsResult
+
+type posFieldsResult struct {
+	fields []posField
+	err    error
+}
+
+// positionalFields returns fields of struct type t with `jsonrpc` tag
+// ordered by position, or nil if there are no such fields.
+//
+// Tag format is `jsonrpc:"pos=N[,optional][,default=JSON]"`, default
+// implies optional and must be last. Positions must start from 0 without
+// gaps and optional fields must follow required ones.
+func positionalFields(t reflect.Type) ([]posField, error) {
+	if r, ok := posFields.Load(t); ok {
+		return r.(posFieldsResult).fields, r.(posFieldsResult).err
+	}
+	fields, err := parsePosFields(t)
+	posFields.Store(t, posFieldsResult{fields, err})
+	return fields, err
+}
+
+func parsePosFields(t reflect.Type) ([]posField, error) {
+	var fields []posField
+	byPos := make(map[int]bool)
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag, ok := sf.Tag.Lookup("jsonrpc")
+		if !ok {
+			continue
+		}
+		bad := errors.New("bad jsonrpc tag of " + t.String() + "." + sf.Name + ": " + tag)
+		f := posField{index: sf.Index, name: sf.Name}
+		if name := strings.Split(sf.Tag.Get("json"), ",")[0]; name != "" && name != "-" {
+			f.name = name
+		}
+		if i := strings.Index(tag, "default="); i >= 0 {
+			f.def, f.optional = json.RawMessage(tag[i+len("default="):]), true
+			if !json.Valid(f.def) {
+				return nil, bad
+			}
+			tag = strings.TrimSuffix(tag[:i], ",")
+		}
+		pos := -1
+		for _, opt := range strings.Split(tag, ",") {
+			switch {
+			case opt == "optional":
+				f.optional = true
+			case strings.HasPrefix(opt, "pos="):
+				n, err := strconv.Atoi(opt[len("pos="):])
+				if err != nil || n < 0 || byPos[n] {
+					return nil, bad
+				}
+				pos = n
+			default:
+				return nil, bad
+			}
+		}
+		if pos < 0 {
+			return nil, bad
+		}
+		byPos[pos] = true
+		for len(fields) <= pos {
+			fields = append(fields, posField{})
+		}
+		fields[pos] = f
+	}
+	for i, f := range fields {
+		if f.index == nil {
+			return nil, fmt.Errorf("no field with jsonrpc tag pos=%d in %s", i, t)
+		}
+		if i > 0 && fields[i-1].optional && !f.optional {
+			return nil, fmt.Errorf("required param %s follows optional one in %s", f.name, t)
+		}
+	}
+	return fields, nil
+}
+
+// unmarshalParams decodes "params" (nil if omitted) into x. If x is a
+// pointer to struct with `jsonrpc:"pos=N"` tags (see positionalFields)
+// then params may be either an object or an array, missing optional
+// params get their default values and missing required params result in
+// error. Returned error is *Error.
+func unmarshalParams(params json.RawMessage, x interface{}) error {
+	t := reflect.TypeOf(x)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	var fields []posField
+	if t != nil && t.Kind() == reflect.Struct {
+		var err error
+		if fields, err = positionalFields(t); err != nil {
+			return NewError(errInternal.Code, err.Error())
+		}
+	}
+	if fields == nil {
+		if params == nil {
+			return nil
+		}
+		if err := json.Unmarshal(params, x); err != nil {
+			return NewError(errParams.Code, err.Error())
+		}
+		return nil
+	}
+
+	// Find struct, allocating pointers if needed.
+	v := reflect.ValueOf(x)
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return NewError(errInternal.Code, "nil params value")
+		}
+		if v = v.Elem(); v.Kind() == reflect.Ptr && v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+	}
+
+	if len(params) > 0 && params[0] == '[' {
+		var arr []json.RawMessage
+		if err := json.Unmarshal(params, &arr); err != nil {
+			return NewError(errParams.Code, err.Error())
+		}
+		if len(arr) > len(fields) {
+			return NewError(errParams.Code, fmt.Sprintf("too many params: %d, want at most %d", len(arr), len(fields)))
+		}
+		for i, f := range fields {
+			var raw json.RawMessage
+			if i < len(arr) && string(arr[i]) != "null" {
+				raw = arr[i]
+			}
+			if err := f.set(v, i, raw); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	var obj map[string]json.RawMessage
+	if params != nil {
+		if err := json.Unmarshal(params, &obj); err != nil {
+			return NewError(errParams.Code, err.Error())
+		}
+		if err := json.Unmarshal(params, x); err != nil {
+			return NewError(errParams.Code, err.Error())
+		}
+	}
+	for i, f := range fields {
+		present := false
+		for key, raw := range obj {
+			if strings.EqualFold(key, f.name) && string(raw) != "null" {
+				present = true
+			}
+		}
+		if !present {
+			if err := f.set(v, i, nil); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// set decodes raw into f of struct v. If raw is nil then f gets default
+// value or, if f is required, error is returned.
+func (f *posField) set(v reflect.Value, pos int, raw json.RawMessage) error {
+	if raw == nil && !f.optional {
+		return NewError(errParams.Code, fmt.Sprintf("missing required param %d (%s)", pos, f.name))
+	}
+	if raw == nil {
+		raw = f.def
+	}
+	if raw == nil {
+		return nil
+	}
+	if err := json.Unmarshal(raw, v.FieldByIndex(f.index).Addr().Interface()); err != nil {
+		return NewError(errParams.Code, fmt.Sprintf("param %d (%s): %v", pos, f.name, err))
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+package jsonrpcf
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/rpc"
+	"reflect"
+	"testing"
+)
+
+type GetBlockArg struct {
+	Hash      string `json:"blockhash" jsonrpc:"pos=0"`
+	Verbosity int    `json:"verbosity" jsonrpc:"pos=1,default=1"`
+	Ctx
+}
+
+func TestUnmarshalParams(t *testing.T) {
+	missing := NewError(errParams.Code, "missing required param 0 (blockhash)")
+	cases := []struct {
+		params string
+		want   GetBlockArg
+		err    *Error
+	}{
+		{`["h"]`, GetBlockArg{Hash: "h", Verbosity: 1}, nil},
+		{`["h",0]`, GetBlockArg{Hash: "h", Verbosity: 0}, nil},
+		{`["h",null]`, GetBlockArg{Hash: "h", Verbosity: 1}, nil},
+		{`{"blockhash":"h"}`, GetBlockArg{Hash: "h", Verbosity: 1}, nil},
+		{`{"blockhash":"h","verbosity":2}`, GetBlockArg{Hash: "h", Verbosity: 2}, nil},
+		{`{"BlockHash":"h","verbosity":null}`, GetBlockArg{Hash: "h", Verbosity: 1}, nil},
+		{``, GetBlockArg{}, missing},
+		{`[]`, GetBlockArg{}, missing},
+		{`{}`, GetBlockArg{}, missing},
+		{`{"verbosity":2}`, GetBlockArg{}, missing},
+		{`[null]`, GetBlockArg{}, missing},
+		{`[null,2]`, GetBlockArg{}, missing},
+		{`{"blockhash":null}`, GetBlockArg{}, missing},
+		{`["h",1,2]`, GetBlockArg{}, NewError(errParams.Code, "too many params: 3, want at most 2")},
+		{`[1]`, GetBlockArg{}, NewError(errParams.Code, "param 0 (blockhash): json: cannot unmarshal number into Go value of type string")},
+	}
+	for _, c := range cases {
+		var params json.RawMessage
+		if c.params != "" {
+			params = json.RawMessage(c.params)
+		}
+		var got GetBlockArg
+		err := unmarshalParams(params, &got)
+		if c.err != nil {
+			if !reflect.DeepEqual(err, c.err) {
+				t.Errorf("%s: err = %v, want %v", c.params, err, c.err)
+			}
+			continue
+		}
+		if err != nil || got != c.want {
+			t.Errorf("%s: got %+v, %v, want %+v", c.params, got, err, c.want)
+		}
+	}
+
+	// Pointer to pointer is allocated.
+	var p *GetBlockArg
+	if err := unmarshalParams(json.RawMessage(`["h"]`), &p); err != nil || p == nil || p.Hash != "h" {
+		t.Errorf("unmarshalParams() into **T = %+v, %v", p, err)
+	}
+}
+
+func TestUnmarshalParamsBadTags(t *testing.T) {
+	bad := []interface{}{
+		&struct {
+			A int `jsonrpc:"pos=x"`
+		}{},
+		&struct {
+			A int `jsonrpc:"optional"`
+		}{},
+		&struct {
+			A int `jsonrpc:"pos=0,required"`
+		}{},
+		&struct {
+			A int `jsonrpc:"pos=0,default=bad"`
+		}{},
+		&struct {
+			A int `jsonrpc:"pos=0"`
+			B int `jsonrpc:"pos=0"`
+		}{},
+		&struct {
+			A int `jsonrpc:"pos=1"`
+		}{},
+		&struct {
+			A int `jsonrpc:"pos=0,optional"`
+			B int `jsonrpc:"pos=1"`
+		}{},
+	}
+	reg := NewRegistry()
+	for _, x := range bad {
+		err := unmarshalParams(json.RawMessage(`[1]`), x)
+		if e, ok := err.(*Error); !ok || e.Code != errInternal.Code {
+			t.Errorf("%T: err = %v, want internal error", x, err)
+		}
+		// Registry checks tags when func is registered.
+		fn := reflect.MakeFunc(reflect.FuncOf([]reflect.Type{reflect.TypeOf(x)}, []reflect.Type{errorType}, false),
+			func([]reflect.Value) []reflect.Value { return []reflect.Value{reflect.Zero(errorType)} })
+		if err := reg.Register("bad", fn.Interface()); err == nil {
+			t.Errorf("%T: Register(), err = nil", x)
+		}
+	}
+}
+
+// Chain is an RPC service for testing positional params binding.
+type Chain struct{}
+
+func (*Chain) GetBlock(arg GetBlockArg, res *string) error {
+	*res = fmt.Sprintf("%s/%d", arg.Hash, arg.Verbosity)
+	return nil
+}
+
+func TestServerPositionalParams(t *testing.T) {
+	srv := rpc.NewServer()
+	srv.Register(&Chain{})
+	reg := NewRegistry()
+	reg.Register("getblock", func(arg *GetBlockArg) (string, error) {
+		return fmt.Sprintf("%s/%d", arg.Hash, arg.Verbosity), nil
+	})
+	opts := []ServerOption{ServerRegistry(reg), ServerMethodAlias("Chain.getblock", "Chain.GetBlock")}
+
+	cases := []struct {
+		params string
+		reply  string
+	}{
+		{`,"params":["h"]`, `{"id":1,"result":"h/1","error":null}`},
+		{`,"params":["h",2]`, `{"id":1,"result":"h/2","error":null}`},
+		{`,"params":{"blockhash":"h","verbosity":0}`, `{"id":1,"result":"h/0","error":null}`},
+		{``, `{"id":1,"error":{"code":-32602,"message":"missing required param 0 (blockhash)"}}`},
+		{`,"params":[]`, `{"id":1,"error":{"code":-32602,"message":"missing required param 0 (blockhash)"}}`},
+		{`,"params":[null,2]`, `{"id":1,"error":{"code":-32602,"message":"missing required param 0 (blockhash)"}}`},
+		{`,"params":["h",1,2]`, `{"id":1,"error":{"code":-32602,"message":"too many params: 3, want at most 2"}}`},
+	}
+	transports, closeTransports := rpcServerTransports(srv, opts...)
+	defer closeTransports()
+	for name, roundTrip := range transports {
+		for _, method := range []string{"Chain.getblock", "getblock"} {
+			for _, c := range cases {
+				req := `{"id":1,"method":"` + method + `"` + c.params + `}`
+				if got := roundTrip(req); !batchEqual(got, c.reply) {
+					t.Errorf("%s: %s:\nexp: %s\ngot: %s", name, req, c.reply, got)
+				}
+			}
+		}
+	}
+}
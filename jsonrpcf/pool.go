@@ -0,0 +1,167 @@
+package jsonrpcf
+
+import (
+	"context"
+	"errors"
+	"net/rpc"
+	"sync"
+)
+
+var _ Caller = &Pool{}
+
+// Pool is a client which keeps several TCP connections to same address and
+// sends each call using connection with least number of calls in flight,
+// so slow reply won't delay replies to other calls.
+//
+// Connections closed by server are replaced with new ones when needed.
+type Pool struct {
+	network string
+	address string
+	opts    []ClientOption
+
+	mu     sync.Mutex // protects following
+	conns  []*poolConn
+	closed bool
+}
+
+type poolConn struct {
+	client   *Client // nil if not connected
+	inflight int
+}
+
+// DialPool connects to a JSON-RPC server at the specified network address
+// using size connections.
+func DialPool(network, address string, size int, opts ...ClientOption) (*Pool, error) {
+	if size < 1 {
+		size = 1
+	}
+	p := &Pool{
+		network: network,
+		address: address,
+		opts:    opts,
+		conns:   make([]*poolConn, size),
+	}
+	for i := range p.conns {
+		client, err := Dial(network, address, opts...)
+		if err != nil {
+			p.Close()
+			return nil, err
+		}
+		p.conns[i] = &poolConn{client: client}
+	}
+	return p, nil
+}
+
+// Call invokes the named function, waits for it to complete, and returns
+// its error status. It's CallContext with background context.
+func (p *Pool) Call(serviceMethod string, args interface{}, reply interface{}) error {
+	return p.CallContext(context.Background(), serviceMethod, args, reply)
+}
+
+// CallContext invokes the named function using least busy connection,
+// waits for it to complete, and returns its error status. See
+// Client.CallContext.
+func (p *Pool) CallContext(ctx context.Context, serviceMethod string, args interface{}, reply interface{}) error {
+	return p.do(func(c *Client) error {
+		return c.CallContext(ctx, serviceMethod, args, reply)
+	})
+}
+
+// Notify try to invoke the named function using least busy connection.
+// It return error only in case it wasn't able to send request.
+func (p *Pool) Notify(serviceMethod string, args interface{}) error {
+	return p.do(func(c *Client) error {
+		return c.Notify(serviceMethod, args)
+	})
+}
+
+// Close closes all connections.
+func (p *Pool) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return rpc.ErrShutdown
+	}
+	p.closed = true
+	for _, pc := range p.conns {
+		if pc != nil && pc.client != nil {
+			pc.client.Close()
+			pc.client = nil
+		}
+	}
+	return nil
+}
+
+// do runs f using least busy connection. If connection was already
+// closed then request wasn't sent, so it's safe to run f once again using
+// new connection.
+func (p *Pool) do(f func(c *Client) error) error {
+	for attempt := 0; ; attempt++ {
+		pc, c, err := p.acquire()
+		if err != nil {
+			return err
+		}
+		err = f(c)
+		p.release(pc, c, err)
+		if attempt > 0 || !errors.Is(err, rpc.ErrShutdown) {
+			return err
+		}
+	}
+}
+
+// acquire returns least busy connection, connecting if needed.
+func (p *Pool) acquire() (*poolConn, *Client, error) {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil, nil, &TransportError{Err: rpc.ErrShutdown}
+	}
+	pc := p.conns[0]
+	for _, c := range p.conns[1:] {
+		if c.inflight < pc.inflight {
+			pc = c
+		}
+	}
+	pc.inflight++
+	c := pc.client
+	p.mu.Unlock()
+	if c != nil {
+		return pc, c, nil
+	}
+
+	c, err := Dial(p.network, p.address, p.opts...)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	switch {
+	case err != nil:
+		pc.inflight--
+		return nil, nil, &TransportError{Err: err}
+	case p.closed:
+		pc.inflight--
+		c.Close()
+		return nil, nil, &TransportError{Err: rpc.ErrShutdown}
+	case pc.client != nil:
+		c.Close()
+		return pc, pc.client, nil
+	}
+	pc.client = c
+	return pc, c, nil
+}
+
+// release returns connection used by call or notification which has
+// finished with err. Dead connection will be removed from pool, even if
+// it was lost after call has succeeded.
+func (p *Pool) release(pc *poolConn, c *Client, err error) {
+	dead := connLost(c, err) || c.codec.isLost()
+	p.mu.Lock()
+	pc.inflight--
+	if dead && pc.client == c {
+		pc.client = nil
+	} else {
+		dead = false
+	}
+	p.mu.Unlock()
+	if dead {
+		c.Close()
+	}
+}
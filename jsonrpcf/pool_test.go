@@ -0,0 +1,157 @@
+package jsonrpcf
+
+import (
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+// tcpServer serves rpc.DefaultServer on TCP and returns listener and
+// function which closes all accepted connections.
+func tcpServer(t *testing.T) (net.Listener, func()) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var mu sync.Mutex
+	var conns []net.Conn
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			mu.Lock()
+			conns = append(conns, conn)
+			mu.Unlock()
+			go ServeConn(conn)
+		}
+	}()
+	drop := func() {
+		mu.Lock()
+		defer mu.Unlock()
+		for _, conn := range conns {
+			conn.Close()
+		}
+		conns = nil
+	}
+	return ln, drop
+}
+
+func TestPool(t *testing.T) {
+	ln, drop := tcpServer(t)
+	defer ln.Close()
+	defer drop()
+
+	pool, err := DialPool("tcp", ln.Addr().String(), 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 30; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			var got int
+			if err := pool.Call("Svc.Sum", [2]int{i, 1}, &got); err != nil || got != i+1 {
+				t.Errorf("Call(%d, 1) = %v, %v", i, got, err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	for _, pc := range pool.conns {
+		if pc.inflight != 0 {
+			t.Errorf("inflight = %d, want 0", pc.inflight)
+		}
+	}
+}
+
+func TestPoolLeastInFlight(t *testing.T) {
+	ln, drop := tcpServer(t)
+	defer ln.Close()
+	defer drop()
+
+	pool, err := DialPool("tcp", ln.Addr().String(), 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+
+	pool.conns[0].inflight = 2
+	pool.conns[1].inflight = 1
+	pool.conns[2].inflight = 3
+	pc, _, err := pool.acquire()
+	if err != nil || pc != pool.conns[1] {
+		t.Errorf("acquire() = %p, %v, want %p", pc, err, pool.conns[1])
+	}
+	if pool.conns[1].inflight != 2 {
+		t.Errorf("inflight = %d, want 2", pool.conns[1].inflight)
+	}
+}
+
+func TestPoolReconnect(t *testing.T) {
+	ln, drop := tcpServer(t)
+	defer ln.Close()
+	defer drop()
+
+	pool, err := DialPool("tcp", ln.Addr().String(), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+
+	var got int
+	if err := pool.Call("Svc.Sum", [2]int{3, 5}, &got); err != nil {
+		t.Fatalf("Call(), err = %v", err)
+	}
+	drop()
+	// Calls may fail until all dead connections are detected.
+	for i := 0; i < 4; i++ {
+		pool.Call("Svc.Sum", [2]int{3, 5}, &got)
+	}
+	got = 0
+	if err := pool.Call("Svc.Sum", [2]int{3, 5}, &got); err != nil || got != 8 {
+		t.Errorf("Call() after reconnect = %v, %v, want 8, nil", got, err)
+	}
+
+	pool.Close()
+	if err := pool.Call("Svc.Sum", [2]int{3, 5}, &got); err == nil {
+		t.Errorf("Call() after Close(), err = nil")
+	}
+}
+
+func TestPoolNotifyReconnect(t *testing.T) {
+	ln, drop := tcpServer(t)
+	defer ln.Close()
+	defer drop()
+
+	pool, err := DialPool("tcp", ln.Addr().String(), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+
+	if err := pool.Notify("Svc.Sum", [2]int{3, 5}); err != nil {
+		t.Fatalf("Notify(), err = %v", err)
+	}
+	c := pool.conns[0].client
+	drop()
+	for deadline := time.Now().Add(time.Second); !c.codec.isLost(); {
+		if time.Now().After(deadline) {
+			t.Fatalf("lost connection wasn't detected")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	// Notify may fail or succeed using dead connection, but it must be
+	// replaced anyway.
+	pool.Notify("Svc.Sum", [2]int{3, 5})
+	for i := 0; i < 3; i++ {
+		if err := pool.Notify("Svc.Sum", [2]int{3, 5}); err != nil {
+			t.Errorf("Notify() after reconnect, err = %v", err)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package jsonrpcf
+
+import (
+	"net/http"
+	"sync"
+)
+
+// Profile describes how to talk to some kind of node: which dialect of
+// JSON-RPC protocol it speaks and which deviations from protocol spec
+// (quirks) it needs to be tolerated.
+//
+// Profile must not be modified after it was registered or given to
+// WithProfile.
+type Profile struct {
+	// Name is used to find profile in registry.
+	Name string
+	// Dialect is used for requests and replies validation.
+	Dialect Dialect
+	// ResultWithError tolerates replies with both "result" and "error"
+	// members in JSONRPC20 dialect, if one of them is null. (JSON-RPC
+	// 1.0 replies always contain both members.)
+	ResultWithError bool
+	// MissingVersion tolerates replies without "jsonrpc" member in
+	// dialects which send it in requests: JSONRPC10Versioned and
+	// JSONRPC20.
+	MissingVersion bool
+	// HTTPErrorReplies lists HTTP status codes (besides 200 OK) of
+	// replies which body contains JSON-RPC reply.
+	HTTPErrorReplies []int
+}
+
+// httpReply returns true if body of HTTP reply with given status code
+// contains JSON-RPC reply.
+func (p *Profile) httpReply(code int) bool {
+	if code == http.StatusOK {
+		return true
+	}
+	for _, c := range p.HTTPErrorReplies {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+// Predefined profiles, available in registry using their names.
+//
+// Nodes of bitcoind family reply to JSON-RPC 1.0 requests without
+// "jsonrpc" member and send errors with HTTP status 500 (or 404 for
+// "method not found"), so their profiles tolerate these quirks. Forks
+// based on Bitcoin Core older than 28.0 also reply to JSON-RPC 2.0
+// requests in JSON-RPC 1.0 format, so their profiles tolerate it in
+// JSONRPC20 dialect (see WithDialect).
+var (
+	// ProfileStrict tolerates no quirks. It's useful for servers
+	// implemented using this package and other spec-compliant servers.
+	ProfileStrict = &Profile{
+		Name:    "strict",
+		Dialect: JSONRPC10,
+	}
+	// ProfileBitcoinCore is for Bitcoin Core. It's the default profile.
+	//
+	// Since 28.0 Bitcoin Core replies to JSON-RPC 2.0 requests
+	// according to spec, so replies in JSON-RPC 1.0 format aren't
+	// tolerated in JSONRPC20 dialect.
+	ProfileBitcoinCore = &Profile{
+		Name:             "bitcoincore",
+		Dialect:          JSONRPC10Versioned,
+		MissingVersion:   true,
+		HTTPErrorReplies: []int{http.StatusInternalServerError, http.StatusNotFound},
+	}
+	// ProfileZcash is for Zcash (zcashd).
+	ProfileZcash = newLegacyProfile("zcash")
+	// ProfileRavencoin is for Ravencoin.
+	ProfileRavencoin = newLegacyProfile("ravencoin")
+	// ProfileLitecoin is for Litecoin Core.
+	ProfileLitecoin = newLegacyProfile("litecoin")
+)
+
+// newLegacyProfile returns profile for node based on Bitcoin Core older
+// than 28.0.
+func newLegacyProfile(name string) *Profile {
+	return &Profile{
+		Name:             name,
+		Dialect:          JSONRPC10Versioned,
+		ResultWithError:  true,
+		MissingVersion:   true,
+		HTTPErrorReplies: []int{http.StatusInternalServerError, http.StatusNotFound},
+	}
+}
+
+var (
+	profilesMu sync.RWMutex
+	profiles   = make(map[string]*Profile)
+)
+
+func init() {
+	for _, p := range []*Profile{ProfileStrict, ProfileBitcoinCore, ProfileZcash, ProfileRavencoin, ProfileLitecoin} {
+		RegisterProfile(p)
+	}
+}
+
+// RegisterProfile adds p to registry, replacing registered profile with
+// same name (if any).
+func RegisterProfile(p *Profile) {
+	profilesMu.Lock()
+	defer profilesMu.Unlock()
+	profiles[p.Name] = p
+}
+
+// LookupProfile returns registered profile with given name or nil.
+func LookupProfile(name string) *Profile {
+	profilesMu.RLock()
+	defer profilesMu.RUnlock()
+	return profiles[name]
+}
+
+// WithProfile makes client talk to node according to profile p
+// (ProfileBitcoinCore by default). It also sets client's dialect to
+// p.Dialect, so use WithDialect after WithProfile to override it.
+//
+// If p is nil then default profile will be used.
+func WithProfile(p *Profile) ClientOption {
+	return func(cfg *clientConfig) {
+		cfg.profile = p
+		if cfg.profile == nil {
+			cfg.profile = ProfileBitcoinCore
+		}
+		cfg.dialect = cfg.profile.Dialect
+	}
+}
@@ -0,0 +1,161 @@
+package jsonrpcf
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestProfileRegistry(t *testing.T) {
+	for _, name := range []string{"strict", "bitcoincore", "zcash", "ravencoin", "litecoin"} {
+		if p := LookupProfile(name); p == nil || p.Name != name {
+			t.Errorf("LookupProfile(%q) = %v", name, p)
+		}
+	}
+	if p := LookupProfile("nosuch"); p != nil {
+		t.Errorf("LookupProfile(nosuch) = %v, want nil", p)
+	}
+
+	custom := &Profile{Name: "custom", Dialect: JSONRPC20, MissingVersion: true}
+	RegisterProfile(custom)
+	if p := LookupProfile("custom"); p != custom {
+		t.Errorf("LookupProfile(custom) = %v, want %v", p, custom)
+	}
+}
+
+func TestProfileQuirks(t *testing.T) {
+	const resp = `{"id":0,"result":null,"error":{"code":-5,"message":"msg"}}`
+	cases := []struct {
+		profile *Profile
+		wantBad bool
+	}{
+		{ProfileStrict, true},
+		{ProfileBitcoinCore, true},
+		{ProfileZcash, false},
+		{ProfileRavencoin, false},
+		{ProfileLitecoin, false},
+		{&Profile{Name: "noversion", MissingVersion: true}, true},
+		{&Profile{Name: "resulterror", ResultWithError: true}, true},
+		{&Profile{Name: "both", MissingVersion: true, ResultWithError: true}, false},
+	}
+	for _, c := range cases {
+		cli, srv := net.Pipe()
+		defer srv.Close()
+		client := NewClient(cli, WithProfile(c.profile), WithDialect(JSONRPC20))
+		defer client.Close()
+
+		go func() {
+			bufio.NewReader(srv).ReadString('\n')
+			srv.Write([]byte(resp + "\n"))
+		}()
+		err := client.Call("Svc.Sum", [2]int{3, 5}, nil)
+		if err == nil {
+			t.Errorf("%s: err = nil", c.profile.Name)
+		} else if isBad := strings.Contains(err.Error(), "bad response"); isBad != c.wantBad {
+			t.Errorf("%s: err = %v", c.profile.Name, err)
+		}
+	}
+}
+
+func TestProfileHTTPErrorReplies(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"result":null,"error":{"code":-8,"message":"Block height out of range"},"id":0}` + "\n"))
+	}))
+	defer ts.Close()
+
+	cases := []struct {
+		profile *Profile
+		want    *Error
+	}{
+		{ProfileZcash, NewError(-8, "Block height out of range")},
+		{ProfileStrict, NewError(errInternal.Code, "bad HTTP Status: 500 Internal Server Error")},
+	}
+	for _, c := range cases {
+		client := NewHTTPClient(ts.URL, WithProfile(c.profile))
+		defer client.Close()
+		err := client.Call("getblockhash", []int{1 << 30}, nil)
+		if err == nil || *ServerError(err) != *c.want {
+			t.Errorf("%s: err = %v, want %v", c.profile.Name, err, c.want)
+		}
+	}
+}
+
+// TestProfiles checks that each quirk of predefined profiles is needed to
+// talk to related node: call must fail if quirk will be removed.
+func TestProfiles(t *testing.T) {
+	const (
+		reply10      = `{"result":8,"error":null,"id":0}`
+		errReply10   = `{"result":null,"error":{"code":-8,"message":"Block height out of range"},"id":0}`
+		wantErr      = `Block height out of range`
+		wantHTTPErr  = `bad HTTP Status: 500 Internal Server Error`
+		wantBadReply = `bad response: ` + reply10
+	)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(errReply10 + "\n"))
+	}))
+	defer ts.Close()
+
+	cases := []struct {
+		profile *Profile
+		dialect Dialect
+		quirk   func(p *Profile) // removes quirk from p
+		call    func(p *Profile, d Dialect) error
+		want    string // error without quirk
+		wantErr string // error with quirk, if any
+	}{
+		{ProfileBitcoinCore, JSONRPC10Versioned, func(p *Profile) { p.MissingVersion = false }, pipeCall(reply10), wantBadReply, ""},
+		{ProfileBitcoinCore, JSONRPC10Versioned, func(p *Profile) { p.HTTPErrorReplies = nil }, httpCall(ts.URL), wantHTTPErr, wantErr},
+		{ProfileZcash, JSONRPC10Versioned, func(p *Profile) { p.MissingVersion = false }, pipeCall(reply10), wantBadReply, ""},
+		{ProfileZcash, JSONRPC10Versioned, func(p *Profile) { p.HTTPErrorReplies = nil }, httpCall(ts.URL), wantHTTPErr, wantErr},
+		{ProfileZcash, JSONRPC20, func(p *Profile) { p.ResultWithError = false }, pipeCall(reply10), wantBadReply, ""},
+		{ProfileRavencoin, JSONRPC10Versioned, func(p *Profile) { p.MissingVersion = false }, pipeCall(reply10), wantBadReply, ""},
+		{ProfileRavencoin, JSONRPC10Versioned, func(p *Profile) { p.HTTPErrorReplies = nil }, httpCall(ts.URL), wantHTTPErr, wantErr},
+		{ProfileRavencoin, JSONRPC20, func(p *Profile) { p.ResultWithError = false }, pipeCall(reply10), wantBadReply, ""},
+		{ProfileLitecoin, JSONRPC10Versioned, func(p *Profile) { p.MissingVersion = false }, pipeCall(reply10), wantBadReply, ""},
+		{ProfileLitecoin, JSONRPC10Versioned, func(p *Profile) { p.HTTPErrorReplies = nil }, httpCall(ts.URL), wantHTTPErr, wantErr},
+		{ProfileLitecoin, JSONRPC20, func(p *Profile) { p.ResultWithError = false }, pipeCall(reply10), wantBadReply, ""},
+	}
+	for i, c := range cases {
+		if err := c.call(c.profile, c.dialect); c.wantErr == "" && err != nil || c.wantErr != "" && (err == nil || ServerError(err).Message != c.wantErr) {
+			t.Errorf("%d: %s: err = %v, want %q", i, c.profile.Name, err, c.wantErr)
+		}
+		p := *c.profile
+		c.quirk(&p)
+		if err := c.call(&p, c.dialect); err == nil || ServerError(err).Message != c.want {
+			t.Errorf("%d: %s without quirk: err = %v, want %q", i, c.profile.Name, err, c.want)
+		}
+	}
+}
+
+// pipeCall returns func which makes a call using client with given
+// profile and dialect to server which replies with reply.
+func pipeCall(reply string) func(p *Profile, d Dialect) error {
+	return func(p *Profile, d Dialect) error {
+		cli, srv := net.Pipe()
+		defer srv.Close()
+		client := NewClient(cli, WithProfile(p), WithDialect(d))
+		defer client.Close()
+		go func() {
+			bufio.NewReader(srv).ReadString('\n')
+			srv.Write([]byte(reply + "\n"))
+		}()
+		return client.Call("Svc.Sum", [2]int{3, 5}, nil)
+	}
+}
+
+// httpCall returns func which makes a call using HTTP client with given
+// profile and dialect to url.
+func httpCall(url string) func(p *Profile, d Dialect) error {
+	return func(p *Profile, d Dialect) error {
+		client := NewHTTPClient(url, WithProfile(p), WithDialect(d))
+		defer client.Close()
+		return client.Call("getblockhash", []int{1 << 30}, nil)
+	}
+}
@@ -497,6 +497,107 @@ func TestServerJSON(t *testing.T) {
 	// }
 }
 
+func TestServerJSON2(t *testing.T) {
+	const (
+		jerrParse2   = `{"jsonrpc":"2.0","id":null,"error":{"code":-32700,"message":"Parse error"}}`
+		jerrRequest2 = `{"jsonrpc":"2.0","id":null,"error":{"code":-32600,"message":"Invalid request"}}`
+	)
+	cases := []struct {
+		in   string
+		want string
+	}{
+		// bad JSON
+		{`x`, jerrParse2},
+		// Version
+		{`{"id":0,"method":"Svc.Sum","params":[3,5]}`, jerrRequest2},
+		{`{"jsonrpc":null,"id":0,"method":"Svc.Sum","params":[3,5]}`, jerrRequest2},
+		{`{"jsonrpc":2.0,"id":0,"method":"Svc.Sum","params":[3,5]}`, jerrRequest2},
+		{`{"jsonrpc":"1.0","id":0,"method":"Svc.Sum","params":[3,5]}`, jerrRequest2},
+		{`{"JSONRPC":"2.0","id":0,"method":"Svc.Sum","params":[3,5]}`, jerrRequest2},
+		{`{"jsonrpc":"2.0","id":0,"method":"Svc.Sum","params":[3,5]}`, `{"jsonrpc":"2.0","id":0,"result":8}`},
+		// extra key
+		{`{"jsonrpc":"2.0","id":0,"method":"Svc.Sum","params":[3,5],"extra":null}`, jerrRequest2},
+		// Id type
+		{`{"jsonrpc":"2.0","id":null,"method":"Svc.Sum"}`, `{"jsonrpc":"2.0","id":null,"result":0}`},
+		{`{"jsonrpc":"2.0","id":"str","method":"Svc.Sum"}`, `{"jsonrpc":"2.0","id":"str","result":0}`},
+		{`{"jsonrpc":"2.0","id":true,"method":"Svc.Sum"}`, jerrRequest2},
+		// Error
+		{
+			`{"jsonrpc":"2.0","id":1,"method":"Svc.Err","params":{}}`,
+			`{"jsonrpc":"2.0","id":1,"error":{"code":-32000,"message":"some issue"}}`,
+		},
+		{
+			`{"jsonrpc":"2.0","id":2,"method":"Svc.Err3","params":{}}`,
+			`{"jsonrpc":"2.0","id":2,"error":{"code":42,"message":"some issue","data":{"one":1,"two":2}}}`,
+		},
+		{
+			`{"jsonrpc":"2.0","id":0,"method":"Svc.Bad","params":[]}`,
+			`{"jsonrpc":"2.0","id":0,"error":{"code":-32601,"message":"rpc: can't find method Svc.Bad"}}`,
+		},
+		// Notifications
+		{
+			`{"jsonrpc":"2.0","method":"Svc.Sum","params":[2,3]}` +
+				`{"jsonrpc":"2.0","id":0,"method":"Svc.Sum","params":[3,5]}`,
+			`{"jsonrpc":"2.0","id":0,"result":8}`,
+		},
+		// Batch
+		{`[]`, jerrRequest2},
+		{`[1]`, `[` + jerrRequest2 + `]`},
+		{
+			`[{"id":0,"method":"Svc.Sum","params":[2,3]}]`,
+			`[` + jerrRequest2 + `]`,
+		},
+		{
+			`[{"jsonrpc":"2.0","method":"Svc.Sum","params":[1,2]},{"jsonrpc":"2.0","method":"Svc.Sum","params":[2,3]}]` +
+				`{"jsonrpc":"2.0","id":3,"method":"Svc.Sum","params":[3,4]}`,
+			`{"jsonrpc":"2.0","id":3,"result":7}`,
+		},
+		{
+			`[` +
+				`{"jsonrpc":"2.0","id":1,"method":"Svc.Sum","params":[1,2]},` +
+				`{"jsonrpc":"2.0","method":"Svc.Sum","params":[3,4]},` +
+				`{"jsonrpc":"2.0","id":2,"method":"Svc.Err2"},` +
+				`{"id":3,"method":"Svc.Sum","params":[3,4]}]`,
+			`[` +
+				jerrRequest2 + `,` +
+				`{"jsonrpc":"2.0","id":1,"result":3},` +
+				`{"jsonrpc":"2.0","id":2,"error":{"code":42,"message":"some issue"}}]`,
+		},
+	}
+
+	for _, c := range cases {
+		cli, srv := net.Pipe()
+		defer cli.Close()
+		go NewServer(nil, ServerJSONRPC2()).ServeConn(srv)
+		buf := bufio.NewReader(cli)
+
+		_, err := cli.Write([]byte(c.in + "\n"))
+		if err != nil {
+			t.Errorf("send err = %v\nsent: %#q", err, c.in)
+			continue
+		}
+		got, err := buf.ReadString('\n')
+		if err != nil {
+			t.Errorf("recv err = %v\nsent: %#q", err, c.in)
+			continue
+		}
+		got = strings.TrimRight(got, "\n")
+
+		var jgot, jwant interface{}
+		if err := json.Unmarshal([]byte(got), &jgot); err != nil {
+			t.Errorf("output err = %v\nsent: %#q\nrecv: %#q", err, c.in, got)
+		}
+		if err := json.Unmarshal([]byte(c.want), &jwant); err != nil {
+			t.Errorf("expect err = %v\nsent: %#q\nwant: %#q", err, c.in, c.want)
+		}
+		sortBatch(jgot)
+		sortBatch(jwant)
+		if !reflect.DeepEqual(jgot, jwant) {
+			t.Errorf("\nsent: %#q\nwant: %#q\nrecv: %#q", c.in, c.want, got)
+		}
+	}
+}
+
 func TestClientResponse(t *testing.T) {
 	var errBadResponseFmt = NewError(-32603, "bad response: %s")
 	cases := []*struct {
@@ -530,7 +631,6 @@ func TestClientResponse(t *testing.T) {
 		{jerrParse, 0.0, errParse},
 		{`{"id":true, "result":0}`, 0.0, errBadResponseFmt},
 		{`{"id":false,"result":0}`, 0.0, errBadResponseFmt},
-		{`{"id":"0",  "result":0}`, 0.0, errBadResponseFmt},
 		{`{"id":[0],  "result":0}`, 0.0, errBadResponseFmt},
 		{`{"id":{},   "result":0}`, 0.0, errBadResponseFmt},
 		// Result type
@@ -625,6 +725,70 @@ func TestClientResponse(t *testing.T) {
 	}
 }
 
+func TestClientDialect(t *testing.T) {
+	cases := []struct {
+		dialect    Dialect
+		wantReq    string
+		wantNotify string
+		resp       string
+		wantOK     bool
+	}{
+		{JSONRPC10, `{"method":"Svc.Sum","params":[3,5],"id":0}`, `{"method":"Svc.Sum","params":[3,5],"id":null}`,
+			`{"id":0,"result":8,"error":null}`, true},
+		{JSONRPC10, ``, ``, `{"id":0,"result":8}`, false},
+		{JSONRPC10, ``, ``, `{"jsonrpc":"1.0","id":0,"result":8,"error":null}`, false},
+		{JSONRPC10, ``, ``, `{"id":0,"result":null,"error":{"code":-5,"message":"msg"}}`, true},
+		{JSONRPC10Versioned, `{"jsonrpc":"1.0","method":"Svc.Sum","params":[3,5],"id":0}`, `{"jsonrpc":"1.0","method":"Svc.Sum","params":[3,5],"id":null}`,
+			`{"jsonrpc":"1.0","id":0,"result":8}`, true},
+		{JSONRPC10Versioned, ``, ``, `{"jsonrpc":"1.0","id":0,"result":8,"error":null}`, true},
+		{JSONRPC10Versioned, ``, ``, `{"id":0,"result":8,"error":null}`, false},
+		{JSONRPC10Versioned, ``, ``, `{"jsonrpc":"2.0","id":0,"result":8}`, false},
+		{JSONRPC10Versioned, ``, ``, `{"id":0,"result":8,"error":{"code":-5,"message":"msg"}}`, false},
+		{JSONRPC20, `{"jsonrpc":"2.0","method":"Svc.Sum","params":[3,5],"id":0}`, `{"jsonrpc":"2.0","method":"Svc.Sum","params":[3,5]}`,
+			`{"jsonrpc":"2.0","id":0,"result":8}`, true},
+		{JSONRPC20, ``, ``, `{"id":0,"result":8}`, false},
+		{JSONRPC20, ``, ``, `{"jsonrpc":"2.0","id":0,"result":8,"error":null}`, false},
+		{JSONRPC20, ``, ``, `{"jsonrpc":"2.0","id":0,"error":null}`, false},
+		{JSONRPC20, ``, ``, `{"jsonrpc":"2.0","id":0,"result":null,"error":{"code":-5,"message":"msg"}}`, false},
+		{JSONRPC20, ``, ``, `{"jsonrpc":"2.0","id":0,"error":{"code":-5,"message":"msg"}}`, true},
+	}
+	for _, c := range cases {
+		cli, srv := net.Pipe()
+		defer srv.Close()
+		client := NewClient(cli, WithProfile(ProfileStrict), WithDialect(c.dialect))
+		defer client.Close()
+		buf := bufio.NewReader(srv)
+
+		go func() {
+			got, _ := buf.ReadString('\n')
+			if c.wantReq != "" && !jsonEqual(got, c.wantReq) {
+				t.Errorf("%v: request\nexp: %#q\ngot: %#q", c.dialect, c.wantReq, got)
+			}
+			srv.Write([]byte(c.resp + "\n"))
+		}()
+		var got int
+		err := client.Call("Svc.Sum", [2]int{3, 5}, &got)
+		if isBad := err != nil && strings.Contains(err.Error(), "bad response"); isBad == c.wantOK {
+			t.Errorf("%v: %s, err = %v", c.dialect, c.resp, err)
+		}
+
+		if c.wantNotify != "" {
+			go client.Notify("Svc.Sum", [2]int{3, 5})
+			if got, _ := buf.ReadString('\n'); !jsonEqual(got, c.wantNotify) {
+				t.Errorf("%v: notification\nexp: %#q\ngot: %#q", c.dialect, c.wantNotify, got)
+			}
+		}
+	}
+}
+
+func jsonEqual(a, b string) bool {
+	var ja, jb interface{}
+	if json.Unmarshal([]byte(a), &ja) != nil || json.Unmarshal([]byte(b), &jb) != nil {
+		return false
+	}
+	return reflect.DeepEqual(ja, jb)
+}
+
 // TODO test for rpc.ErrShutdown && io.ErrUnexpectedEOF
 
 func TestClientRequest(t *testing.T) {
@@ -0,0 +1,338 @@
+package jsonrpcf
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/rpc"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var _ Caller = &ReconnectClient{}
+
+// ErrNotConnected is wrapped in *TransportError returned by
+// ReconnectClient when it's not connected to server.
+var ErrNotConnected = errors.New("not connected")
+
+// ConnState is a state of ReconnectClient's connection.
+type ConnState int
+
+// Connection states.
+const (
+	StateConnecting   ConnState = iota // dialing server
+	StateConnected                     // connected, calls can be made
+	StateDisconnected                  // connection lost or dial failed, waiting before next dial
+	StateClosed                        // client was closed
+)
+
+// String returns state name.
+func (s ConnState) String() string {
+	switch s {
+	case StateConnecting:
+		return "connecting"
+	case StateConnected:
+		return "connected"
+	case StateDisconnected:
+		return "disconnected"
+	case StateClosed:
+		return "closed"
+	default:
+		return "ConnState(" + strconv.Itoa(int(s)) + ")"
+	}
+}
+
+// InFlightPolicy defines what ReconnectClient does with calls when
+// connection is lost.
+type InFlightPolicy int
+
+const (
+	// FailInFlight makes calls in flight fail with *TransportError, and
+	// calls made while client is not connected fail immediately with
+	// *TransportError wrapping ErrNotConnected. This is the default.
+	FailInFlight InFlightPolicy = iota
+	// RequeueInFlight makes calls in flight and calls made while client
+	// is not connected wait for new connection (until their context is
+	// done) and then (re)send them. Use it only if all methods are
+	// idempotent: call in flight may be already executed by server.
+	RequeueInFlight
+)
+
+// ReconnectOption configures ReconnectClient created by
+// NewReconnectClient.
+type ReconnectOption func(*reconnectConfig)
+
+type reconnectConfig struct {
+	clientOpts []ClientOption
+	backoff    RetryPolicy
+	inflight   InFlightPolicy
+	onState    func(ConnState, error)
+}
+
+// ReconnectClientOptions sets options for clients created for each new
+// connection.
+func ReconnectClientOptions(opts ...ClientOption) ReconnectOption {
+	return func(cfg *reconnectConfig) {
+		cfg.clientOpts = opts
+	}
+}
+
+// ReconnectBackoff sets delay before redial: it starts with min (100ms by
+// default) and doubles after each failed dial up to max (30s by default).
+func ReconnectBackoff(min, max time.Duration) ReconnectOption {
+	return func(cfg *reconnectConfig) {
+		cfg.backoff.InitialBackoff = min
+		cfg.backoff.MaxBackoff = max
+	}
+}
+
+// ReconnectInFlight sets policy for calls when connection is lost
+// (FailInFlight by default).
+func ReconnectInFlight(policy InFlightPolicy) ReconnectOption {
+	return func(cfg *reconnectConfig) {
+		cfg.inflight = policy
+	}
+}
+
+// ReconnectOnState sets callback called each time connection state
+// changes, with error which caused disconnect (if any). Callback must not
+// block.
+func ReconnectOnState(f func(state ConnState, err error)) ReconnectOption {
+	return func(cfg *reconnectConfig) {
+		cfg.onState = f
+	}
+}
+
+// ReconnectClient is a client which connects to TCP server like Dial and
+// dials again (with backoff) each time connection is lost.
+type ReconnectClient struct {
+	network string
+	address string
+	cfg     *reconnectConfig
+	done    chan struct{}
+
+	mu      sync.Mutex // protects following
+	client  *Client    // nil if not connected
+	state   ConnState
+	err     error         // last dial or connection error
+	ready   chan struct{} // closed when connected or closed
+	closing bool
+}
+
+// NewReconnectClient returns a new ReconnectClient which connects to a
+// JSON-RPC server at the specified network address in background.
+func NewReconnectClient(network, address string, opts ...ReconnectOption) *ReconnectClient {
+	cfg := &reconnectConfig{
+		backoff: RetryPolicy{
+			InitialBackoff: 100 * time.Millisecond,
+			MaxBackoff:     30 * time.Second,
+			Jitter:         0.2,
+		},
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	r := &ReconnectClient{
+		network: network,
+		address: address,
+		cfg:     cfg,
+		done:    make(chan struct{}),
+		state:   StateConnecting,
+		ready:   make(chan struct{}),
+	}
+	go r.loop()
+	return r
+}
+
+// State returns current connection state.
+func (r *ReconnectClient) State() ConnState {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.state
+}
+
+// Call invokes the named function, waits for it to complete, and returns
+// its error status. It's CallContext with background context.
+func (r *ReconnectClient) Call(serviceMethod string, args interface{}, reply interface{}) error {
+	return r.CallContext(context.Background(), serviceMethod, args, reply)
+}
+
+// CallContext invokes the named function, waits for it to complete, and
+// returns its error status. See Client.CallContext and InFlightPolicy.
+func (r *ReconnectClient) CallContext(ctx context.Context, serviceMethod string, args interface{}, reply interface{}) error {
+	for {
+		c, err := r.connected(ctx, r.cfg.inflight == RequeueInFlight)
+		if err != nil {
+			return err
+		}
+		err = c.CallContext(ctx, serviceMethod, args, reply)
+		if r.cfg.inflight != RequeueInFlight || ctx.Err() != nil || !connLost(c, err) {
+			return err
+		}
+		r.forget(c)
+	}
+}
+
+// Notify try to invoke the named function. It return error only in case
+// it wasn't able to send request (including when client isn't connected).
+func (r *ReconnectClient) Notify(serviceMethod string, args interface{}) error {
+	c, err := r.connected(context.Background(), false)
+	if err != nil {
+		return err
+	}
+	return c.Notify(serviceMethod, args)
+}
+
+// Close closes connection and stops redialing.
+func (r *ReconnectClient) Close() error {
+	r.mu.Lock()
+	if r.closing {
+		r.mu.Unlock()
+		return rpc.ErrShutdown
+	}
+	r.closing = true
+	close(r.done)
+	if r.client != nil {
+		r.client.Close()
+		r.client = nil
+	}
+	r.mu.Unlock()
+	r.setState(StateClosed, nil)
+	return nil
+}
+
+// connected returns client for current connection. If not connected it
+// either returns error or waits for connection.
+func (r *ReconnectClient) connected(ctx context.Context, wait bool) (*Client, error) {
+	for {
+		r.mu.Lock()
+		c, ready, closing, err := r.client, r.ready, r.closing, r.err
+		r.mu.Unlock()
+		switch {
+		case closing:
+			return nil, &TransportError{Err: rpc.ErrShutdown}
+		case c != nil:
+			return c, nil
+		case !wait:
+			if err == nil {
+				err = ErrNotConnected
+			}
+			return nil, &TransportError{Err: err}
+		}
+		select {
+		case <-ready:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// forget removes client c for lost connection, so calls will wait for
+// new connection.
+func (r *ReconnectClient) forget(c *Client) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.client != c {
+		return
+	}
+	r.client = nil
+	select {
+	case <-r.ready:
+		r.ready = make(chan struct{})
+	default:
+	}
+}
+
+// setState changes state and calls callback.
+func (r *ReconnectClient) setState(state ConnState, err error) {
+	r.mu.Lock()
+	if r.closing && state != StateClosed || r.state == state {
+		r.mu.Unlock()
+		return
+	}
+	r.state = state
+	if err != nil {
+		r.err = err
+	}
+	switch state {
+	case StateConnected, StateClosed:
+		select {
+		case <-r.ready:
+		default:
+			close(r.ready)
+		}
+	default:
+		select {
+		case <-r.ready:
+			r.ready = make(chan struct{})
+		default:
+		}
+	}
+	r.mu.Unlock()
+
+	if r.cfg.onState != nil {
+		r.cfg.onState(state, err)
+	}
+}
+
+// loop dials server and waits until connection will be lost.
+func (r *ReconnectClient) loop() {
+	var backoff time.Duration
+	for {
+		r.setState(StateConnecting, nil)
+		conn, err := net.Dial(r.network, r.address)
+		if err == nil {
+			backoff = 0
+			w := &watchedConn{Conn: conn, lost: make(chan struct{})}
+			c := NewClient(w, r.cfg.clientOpts...)
+			r.mu.Lock()
+			if r.closing {
+				r.mu.Unlock()
+				c.Close()
+				return
+			}
+			r.client, r.err = c, nil
+			r.mu.Unlock()
+			r.setState(StateConnected, nil)
+
+			select {
+			case <-r.done:
+				return
+			case <-w.lost:
+			}
+			r.forget(c)
+			c.Close()
+			err = w.err
+		}
+		r.setState(StateDisconnected, err)
+
+		backoff = r.cfg.backoff.backoff(backoff)
+		timer := time.NewTimer(r.cfg.backoff.jitter(backoff))
+		select {
+		case <-r.done:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// watchedConn closes lost when reading from conn fails.
+type watchedConn struct {
+	net.Conn
+	once sync.Once
+	lost chan struct{}
+	err  error
+}
+
+func (c *watchedConn) Read(buf []byte) (int, error) {
+	n, err := c.Conn.Read(buf)
+	if err != nil {
+		c.once.Do(func() {
+			c.err = err
+			close(c.lost)
+		})
+	}
+	return n, err
+}
@@ -0,0 +1,141 @@
+package jsonrpcf
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+func waitState(t *testing.T, states chan ConnState, want ConnState) {
+	t.Helper()
+	timeout := time.After(2 * time.Second)
+	for {
+		select {
+		case state := <-states:
+			if state == want {
+				return
+			}
+		case <-timeout:
+			t.Fatalf("no %v state", want)
+		}
+	}
+}
+
+func TestReconnectClient(t *testing.T) {
+	ln, drop := tcpServer(t)
+	defer ln.Close()
+	defer drop()
+
+	states := make(chan ConnState, 64)
+	var lostErr error
+	client := NewReconnectClient("tcp", ln.Addr().String(),
+		ReconnectBackoff(time.Millisecond, 10*time.Millisecond),
+		ReconnectOnState(func(state ConnState, err error) {
+			if state == StateDisconnected {
+				lostErr = err
+			}
+			states <- state
+		}),
+	)
+	defer client.Close()
+	waitState(t, states, StateConnected)
+
+	var got int
+	if err := client.Call("Svc.Sum", [2]int{3, 5}, &got); err != nil || got != 8 {
+		t.Errorf("Call() = %v, %v, want 8, nil", got, err)
+	}
+
+	drop()
+	waitState(t, states, StateDisconnected)
+	if lostErr == nil {
+		t.Errorf("disconnected without error")
+	}
+	waitState(t, states, StateConnected)
+	if err := client.Call("Svc.Sum", [2]int{3, 5}, &got); err != nil || got != 8 {
+		t.Errorf("Call() after reconnect = %v, %v, want 8, nil", got, err)
+	}
+
+	client.Close()
+	waitState(t, states, StateClosed)
+	if err := client.Call("Svc.Sum", [2]int{3, 5}, &got); err == nil {
+		t.Errorf("Call() after Close(), err = nil")
+	}
+}
+
+func TestReconnectClientPolicy(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	client := NewReconnectClient("tcp", addr, ReconnectBackoff(time.Millisecond, 10*time.Millisecond))
+	var terr *TransportError
+	if err := client.Call("Svc.Sum", [2]int{3, 5}, nil); !errors.As(err, &terr) {
+		t.Errorf("Call() while not connected, err = %v", err)
+	}
+	client.Close()
+
+	client = NewReconnectClient("tcp", addr,
+		ReconnectBackoff(time.Millisecond, 10*time.Millisecond),
+		ReconnectInFlight(RequeueInFlight),
+	)
+	defer client.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := client.CallContext(ctx, "Svc.Sum", [2]int{3, 5}, nil); err != context.DeadlineExceeded {
+		t.Errorf("CallContext() while not connected, err = %v", err)
+	}
+
+	lnc := make(chan net.Listener, 1)
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		ln, err := net.Listen("tcp", addr)
+		if err != nil {
+			t.Errorf("Listen(%s), err = %v", addr, err)
+			close(lnc)
+			return
+		}
+		lnc <- ln
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go ServeConn(conn)
+		}
+	}()
+	ctx, cancel = context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	var got int
+	if err := client.CallContext(ctx, "Svc.Sum", [2]int{3, 5}, &got); err != nil || got != 8 {
+		t.Errorf("CallContext() queued until connected = %v, %v, want 8, nil", got, err)
+	}
+	if ln := <-lnc; ln != nil {
+		ln.Close()
+	}
+}
+
+func TestReconnectClientRequeueReset(t *testing.T) {
+	ln := resetServer(t, 1)
+	defer ln.Close()
+
+	states := make(chan ConnState, 64)
+	client := NewReconnectClient("tcp", ln.Addr().String(),
+		ReconnectBackoff(time.Millisecond, 10*time.Millisecond),
+		ReconnectInFlight(RequeueInFlight),
+		ReconnectOnState(func(state ConnState, err error) { states <- state }),
+	)
+	defer client.Close()
+	waitState(t, states, StateConnected)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	var got int
+	if err := client.CallContext(ctx, "Svc.Sum", [2]int{3, 5}, &got); err != nil || got != 8 {
+		t.Errorf("CallContext() requeued after reset = %v, %v, want 8, nil", got, err)
+	}
+}
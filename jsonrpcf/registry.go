@@ -0,0 +1,194 @@
+package jsonrpcf
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+var (
+	contextType     = reflect.TypeOf((*context.Context)(nil)).Elem()
+	errorType       = reflect.TypeOf((*error)(nil)).Elem()
+	unmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+)
+
+// Registry is a set of RPC methods implemented by plain funcs, served
+// by server along with net/rpc methods (see ServerRegistry). Unlike
+// net/rpc methods funcs may have any amount of params, and errors
+// returned by funcs are sent without converting them to string first.
+type Registry struct {
+	mu    sync.RWMutex
+	funcs map[string]*registryFunc
+}
+
+// registryFunc is a func registered in Registry.
+type registryFunc struct {
+	fn     reflect.Value
+	ctx    bool           // first param is context.Context
+	params []reflect.Type // params except ctx
+	whole  bool           // single param gets whole "params"
+	result bool           // returns result in addition to error
+}
+
+// NewRegistry returns a new empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{funcs: make(map[string]*registryFunc)}
+}
+
+// Register makes fn available as RPC method with given name (like
+// "Svc.Method" or "getblockcount"). Method with same name registered in
+// net/rpc server will be hidden.
+//
+// Fn must return either (R, error) or error, where R is any type
+// supported by json.Marshal. If first param of fn is context.Context then
+// it gets request context (see WithContext). Other params are decoded from
+// "params" of request:
+//
+//   - single param of struct, map, slice or array type (or pointer to such
+//     type, or any type implementing json.Unmarshaler) gets whole
+//     "params", like param of net/rpc method;
+//   - otherwise "params" must be an array with at most one element per
+//     param, missing trailing params get zero values.
+//
+// Register fails if param of struct type has malformed `jsonrpc` tags
+// (see "Using positional parameters of different types" in package
+// documentation).
+//
+// Panic in fn doesn't crash server: it's returned as internal error.
+//
+// Examples:
+//
+//	func(ctx context.Context, arg NameArg) (NameRes, error)
+//	func(ctx context.Context, height int64, verbose bool) (*Block, error)
+//	func() (int64, error)
+func (r *Registry) Register(name string, fn interface{}) error {
+	v, t := reflect.ValueOf(fn), reflect.TypeOf(fn)
+	if name == "" || t == nil || t.Kind() != reflect.Func {
+		return errors.New("bad func for method: " + name)
+	}
+	if t.IsVariadic() || t.NumOut() < 1 || t.NumOut() > 2 || t.Out(t.NumOut()-1) != errorType {
+		return errors.New("bad func signature for method " + name + ": " + t.String())
+	}
+	f := &registryFunc{fn: v, result: t.NumOut() == 2}
+	for i := 0; i < t.NumIn(); i++ {
+		if i == 0 && t.In(i) == contextType {
+			f.ctx = true
+			continue
+		}
+		f.params = append(f.params, t.In(i))
+	}
+	f.whole = len(f.params) == 1 && wholeParams(f.params[0])
+	if f.whole {
+		t := f.params[0]
+		for t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		if t.Kind() == reflect.Struct {
+			if _, err := positionalFields(t); err != nil {
+				return fmt.Errorf("bad param of method %s: %v", name, err)
+			}
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.funcs[name]; ok {
+		return errors.New("method already registered: " + name)
+	}
+	r.funcs[name] = f
+	return nil
+}
+
+// wholeParams returns true if param of type t should get whole "params".
+func wholeParams(t reflect.Type) bool {
+	if t.Implements(unmarshalerType) || reflect.PtrTo(t).Implements(unmarshalerType) {
+		return true
+	}
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Struct, reflect.Map, reflect.Slice, reflect.Array:
+		return true
+	}
+	return false
+}
+
+// lookup returns func registered for method or nil. It's safe to call on
+// nil Registry.
+func (r *Registry) lookup(method string) *registryFunc {
+	if r == nil {
+		return nil
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.funcs[method]
+}
+
+// ServerRegistry makes server serve methods registered in r in addition
+// to methods of net/rpc server. Methods may be registered in r after
+// server has started.
+func ServerRegistry(r *Registry) ServerOption {
+	return func(cfg *serverConfig) {
+		cfg.registry = r
+	}
+}
+
+// call calls f using call's context and params. Panic in f is returned
+// as internal error.
+func (f *registryFunc) call(call *ServerCall) (res interface{}, err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			res, err = nil, NewError(errInternal.Code, fmt.Sprintf("panic in %s: %v", call.Method, v))
+		}
+	}()
+	ctx := call.Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	args := make([]reflect.Value, 0, len(f.params)+1)
+	if f.ctx {
+		args = append(args, reflect.ValueOf(&ctx).Elem())
+	}
+	if f.whole {
+		v := reflect.New(f.params[0])
+		if err := unmarshalParams(call.Params, v.Interface()); err != nil {
+			return nil, err
+		}
+		if x, ok := v.Interface().(WithContext); ok {
+			x.SetContext(ctx)
+		}
+		args = append(args, v.Elem())
+	} else {
+		var raws []json.RawMessage
+		if call.Params != nil {
+			if err := json.Unmarshal(call.Params, &raws); err != nil {
+				return nil, NewError(errParams.Code, err.Error())
+			}
+		}
+		if len(raws) > len(f.params) {
+			return nil, NewError(errParams.Code, fmt.Sprintf("too many params: %d, want at most %d", len(raws), len(f.params)))
+		}
+		for i, t := range f.params {
+			v := reflect.New(t)
+			if i < len(raws) {
+				if err := json.Unmarshal(raws[i], v.Interface()); err != nil {
+					return nil, NewError(errParams.Code, fmt.Sprintf("param %d: %v", i+1, err))
+				}
+			}
+			args = append(args, v.Elem())
+		}
+	}
+
+	out := f.fn.Call(args)
+	if err, _ := out[len(out)-1].Interface().(error); err != nil {
+		return nil, err
+	}
+	if f.result {
+		return out[0].Interface(), nil
+	}
+	return nil, nil
+}
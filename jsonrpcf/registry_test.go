@@ -0,0 +1,105 @@
+package jsonrpcf
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestRegistryRegister(t *testing.T) {
+	r := NewRegistry()
+	bad := []interface{}{
+		nil,
+		42,
+		func() {},
+		func() int { return 0 },
+		func() (int, int) { return 0, 0 },
+		func() (int, int, error) { return 0, 0, nil },
+		func(...int) error { return nil },
+	}
+	for _, fn := range bad {
+		if err := r.Register("bad", fn); err == nil {
+			t.Errorf("Register(%T), err = nil", fn)
+		}
+	}
+	if err := r.Register("", func() error { return nil }); err == nil {
+		t.Errorf("Register() with empty name, err = nil")
+	}
+	if err := r.Register("ok", func() error { return nil }); err != nil {
+		t.Errorf("Register(), err = %v", err)
+	}
+	if err := r.Register("ok", func() error { return nil }); err == nil {
+		t.Errorf("Register() with same name, err = nil")
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	r := NewRegistry()
+	for name, fn := range map[string]interface{}{
+		"sum": func(ctx context.Context, a, b int) (int, error) {
+			return a + b, nil
+		},
+		"name": func(arg NameArg) (*NameRes, error) {
+			return &NameRes{arg.Fname + " " + arg.Lname}, nil
+		},
+		"ctx": func(ctx context.Context) (bool, error) {
+			return ctx != nil, nil
+		},
+		"fail": func() error {
+			return errors.New(`{"code":1}`)
+		},
+		"wrapped": func() (int, error) {
+			return 0, fmt.Errorf("wrapped: %w", &Error{42, "some issue", []int{1}})
+		},
+		"Svc.Sum": func(vals [2]int) (int, error) {
+			return vals[0] * vals[1], nil
+		},
+		"crash": func() (int, error) {
+			panic("boom")
+		},
+	} {
+		if err := r.Register(name, fn); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cases := []struct {
+		req   string
+		reply string
+	}{
+		{`{"id":1,"method":"sum","params":[3,5]}`, `{"id":1,"result":8,"error":null}`},
+		{`{"id":1,"method":"sum","params":[3]}`, `{"id":1,"result":3,"error":null}`},
+		{`{"id":1,"method":"sum"}`, `{"id":1,"result":0,"error":null}`},
+		{`{"id":1,"method":"sum","params":[1,2,3]}`, `{"id":1,"error":{"code":-32602,"message":"too many params: 3, want at most 2"}}`},
+		{`{"id":1,"method":"sum","params":[1,"2"]}`, `{"id":1,"error":{"code":-32602,"message":"param 2: json: cannot unmarshal string into Go value of type int"}}`},
+		{`{"id":1,"method":"sum","params":{"a":1}}`, `{"id":1,"error":{"code":-32602,"message":"json: cannot unmarshal object into Go value of type []json.RawMessage"}}`},
+		{`{"id":1,"method":"name","params":{"Fname":"First","Lname":"Last"}}`, `{"id":1,"result":{"Name":"First Last"},"error":null}`},
+		{`{"id":1,"method":"ctx","params":[]}`, `{"id":1,"result":true,"error":null}`},
+		{`{"id":1,"method":"fail","params":[]}`, `{"id":1,"error":{"code":-32000,"message":"{\"code\":1}"}}`},
+		{`{"id":1,"method":"wrapped","params":[]}`, `{"id":1,"error":{"code":42,"message":"some issue","data":[1]}}`},
+		{`{"id":1,"method":"crash","params":[]}`, `{"id":1,"error":{"code":-32603,"message":"panic in crash: boom"}}`},
+		{`{"id":1,"method":"Svc.Sum","params":[3,5]}`, `{"id":1,"result":15,"error":null}`},
+		{`{"id":1,"method":"Svc.SumAll","params":[3,5]}`, `{"id":1,"result":8,"error":null}`},
+		{
+			`[{"id":1,"method":"sum","params":[1,2]},{"id":2,"method":"Svc.SumAll","params":[1,2,3]}]`,
+			`[{"id":1,"result":3,"error":null},{"id":2,"result":6,"error":null}]`,
+		},
+	}
+	for _, opts := range [][]ServerOption{
+		{ServerRegistry(r)},
+		{ServerRegistry(r), ServerInterceptors(func(call *ServerCall, next ServerHandler) (interface{}, error) {
+			return next(call)
+		})},
+	} {
+		transports, closeTransports := serverTransports(opts...)
+		for name, roundTrip := range transports {
+			for _, c := range cases {
+				if got := roundTrip(c.req); !batchEqual(got, c.reply) {
+					t.Errorf("%s: %s:\nexp: %s\ngot: %s", name, c.req, c.reply, got)
+				}
+			}
+		}
+		closeTransports()
+	}
+}
@@ -0,0 +1,166 @@
+package jsonrpcf
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+// Error codes used by bitcoind family nodes which are worth retrying.
+const (
+	// CodeInWarmup is returned while node is starting (RPC_IN_WARMUP).
+	CodeInWarmup = -28
+)
+
+// RetryPolicy describes which failed calls made using client.Call() and
+// client.CallContext() should be retried and how.
+//
+// Only calls to methods listed in Methods are retried, so non-idempotent
+// methods like sendrawtransaction are never retried unless explicitly
+// allowed. Batch requests and notifications are never retried.
+//
+// RetryPolicy must not be modified after it was given to WithRetry.
+type RetryPolicy struct {
+	// MaxAttempts limits number of attempts, including first one.
+	// Values less than 2 disable retries.
+	MaxAttempts int
+	// MaxElapsed limits total time spent on call, including all attempts
+	// and delays between them: no attempt will be made if it's already
+	// known delay before it will exceed this limit. 0 means no limit.
+	MaxElapsed time.Duration
+	// InitialBackoff is a delay before second attempt. Each next delay
+	// is Multiplier (2 if 0) times longer, but not longer than
+	// MaxBackoff (if not 0).
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	Multiplier     float64
+	// Jitter randomly shortens each delay by up to Jitter part of it,
+	// to avoid many clients retrying at same time. It must be in range
+	// from 0 (no jitter) to 1.
+	Jitter float64
+
+	// Methods lists methods allowed to be retried.
+	Methods []string
+	// Codes lists JSON-RPC error codes (like CodeInWarmup) which are
+	// worth retrying.
+	Codes []int
+	// HTTPStatuses lists HTTP status codes which are worth retrying
+	// (http.StatusServiceUnavailable if nil).
+	HTTPStatuses []int
+	// Retryable, if not nil, replaces default classification of errors.
+	// By default error is retryable if it's an *Error with code listed in
+	// Codes, or it's a *TransportError caused by refused connection or
+	// *HTTPError with status listed in HTTPStatuses.
+	Retryable func(err error) bool
+}
+
+// WithRetry makes client retry failed calls according to p. Timeout set
+// by WithTimeout and WithMethodTimeout is applied to each attempt.
+func WithRetry(p *RetryPolicy) ClientOption {
+	return func(cfg *clientConfig) {
+		cfg.retry = p
+	}
+}
+
+// allowed returns true if calls to method may be retried.
+func (p *RetryPolicy) allowed(method string) bool {
+	if p == nil || p.MaxAttempts < 2 {
+		return false
+	}
+	for _, m := range p.Methods {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
+
+// retryable returns true if call failed with err is worth retrying.
+func (p *RetryPolicy) retryable(err error) bool {
+	if p.Retryable != nil {
+		return p.Retryable(err)
+	}
+	var rpcerr *Error
+	if errors.As(err, &rpcerr) {
+		for _, code := range p.Codes {
+			if code == rpcerr.Code {
+				return true
+			}
+		}
+		return false
+	}
+	var terr *TransportError
+	if !errors.As(err, &terr) {
+		return false
+	}
+	if errors.Is(terr.Err, syscall.ECONNREFUSED) {
+		return true
+	}
+	var herr *HTTPError
+	if errors.As(terr.Err, &herr) {
+		statuses := p.HTTPStatuses
+		if statuses == nil {
+			statuses = []int{http.StatusServiceUnavailable}
+		}
+		for _, status := range statuses {
+			if status == herr.StatusCode {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// backoff returns delay before next attempt, given delay before previous
+// one (0 for first attempt).
+func (p *RetryPolicy) backoff(prev time.Duration) time.Duration {
+	d := p.InitialBackoff
+	if prev > 0 {
+		m := p.Multiplier
+		if m == 0 {
+			m = 2
+		}
+		d = time.Duration(float64(prev) * m)
+	}
+	if p.MaxBackoff > 0 && d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+	return d
+}
+
+// jitter returns randomly shortened delay d.
+func (p *RetryPolicy) jitter(d time.Duration) time.Duration {
+	if p.Jitter <= 0 {
+		return d
+	}
+	return d - time.Duration(p.Jitter*rand.Float64()*float64(d))
+}
+
+// do calls f until it succeed or p decides to stop retrying. It returns
+// last error returned by f, or ctx.Err() if ctx is done while waiting for
+// next attempt.
+func (p *RetryPolicy) do(ctx context.Context, f func() error) error {
+	start := time.Now()
+	var backoff time.Duration
+	for attempt := 1; ; attempt++ {
+		err := f()
+		if err == nil || attempt >= p.MaxAttempts || !p.retryable(err) {
+			return err
+		}
+		backoff = p.backoff(backoff)
+		d := p.jitter(backoff)
+		if p.MaxElapsed > 0 && time.Since(start)+d > p.MaxElapsed {
+			return err
+		}
+		timer := time.NewTimer(d)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+}
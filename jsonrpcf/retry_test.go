@@ -0,0 +1,132 @@
+package jsonrpcf
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// warmupServer replies to first fails requests with given HTTP status (or
+// with RPC_IN_WARMUP error if status is 500) and then with result 8.
+func warmupServer(fails int32, status int) (*httptest.Server, *int32) {
+	var n int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		var req struct{ ID *uint64 }
+		json.Unmarshal(body, &req)
+		w.Header().Set("Content-Type", "application/json")
+		if atomic.AddInt32(&n, 1) <= fails {
+			w.WriteHeader(status)
+			if status == http.StatusInternalServerError {
+				fmt.Fprintf(w, `{"id":%d,"result":null,"error":{"code":-28,"message":"Loading block index..."}}`, *req.ID)
+			}
+			return
+		}
+		fmt.Fprintf(w, `{"id":%d,"result":8,"error":null}`, *req.ID)
+	}))
+	return ts, &n
+}
+
+func TestRetry(t *testing.T) {
+	policy := &RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: time.Millisecond,
+		Jitter:         0.5,
+		Methods:        []string{"getblockcount"},
+		Codes:          []int{CodeInWarmup},
+	}
+	cases := []struct {
+		method    string
+		fails     int32
+		status    int
+		wantCalls int32
+		wantErr   bool
+	}{
+		{"getblockcount", 2, http.StatusServiceUnavailable, 3, false},
+		{"getblockcount", 2, http.StatusInternalServerError, 3, false},
+		{"getblockcount", 3, http.StatusServiceUnavailable, 3, true},
+		{"getblockcount", 1, http.StatusBadGateway, 1, true},
+		{"sendrawtransaction", 1, http.StatusServiceUnavailable, 1, true},
+		{"sendrawtransaction", 1, http.StatusInternalServerError, 1, true},
+	}
+	for _, c := range cases {
+		ts, n := warmupServer(c.fails, c.status)
+		client := NewHTTPClient(ts.URL, WithRetry(policy))
+		var got int
+		err := client.Call(c.method, []int{}, &got)
+		if (err != nil) != c.wantErr || !c.wantErr && got != 8 {
+			t.Errorf("%s, %d fails with %d: Call() = %v, %v", c.method, c.fails, c.status, got, err)
+		}
+		if calls := atomic.LoadInt32(n); calls != c.wantCalls {
+			t.Errorf("%s, %d fails with %d: %d calls, want %d", c.method, c.fails, c.status, calls, c.wantCalls)
+		}
+		client.Close()
+		ts.Close()
+	}
+}
+
+func TestRetryErrors(t *testing.T) {
+	ts, _ := warmupServer(1, http.StatusServiceUnavailable)
+	defer ts.Close()
+	client := NewHTTPClient(ts.URL)
+	defer client.Close()
+	err := client.Call("getblockcount", []int{}, nil)
+	var terr *TransportError
+	var herr *HTTPError
+	if !errors.As(err, &terr) || !errors.As(err, &herr) || herr.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("Call(), err = %#v, want HTTP 503", err)
+	}
+
+	policy := &RetryPolicy{}
+	if !policy.retryable(&TransportError{Err: herr}) {
+		t.Errorf("HTTP 503 is not retryable")
+	}
+	if policy.retryable(NewError(CodeInWarmup, "Loading")) {
+		t.Errorf("code not listed in Codes is retryable")
+	}
+	if policy.retryable(errors.New("other")) {
+		t.Errorf("unknown error is retryable")
+	}
+}
+
+func TestRetryBudget(t *testing.T) {
+	ts, n := warmupServer(100, http.StatusServiceUnavailable)
+	defer ts.Close()
+	client := NewHTTPClient(ts.URL, WithRetry(&RetryPolicy{
+		MaxAttempts:    100,
+		MaxElapsed:     50 * time.Millisecond,
+		InitialBackoff: 20 * time.Millisecond,
+		Methods:        []string{"getblockcount"},
+	}))
+	defer client.Close()
+	if err := client.Call("getblockcount", []int{}, nil); err == nil {
+		t.Errorf("Call(), err = nil")
+	}
+	// Delays: 20ms, then 40ms which exceeds budget.
+	if calls := atomic.LoadInt32(n); calls != 2 {
+		t.Errorf("%d calls, want 2", calls)
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	p := &RetryPolicy{InitialBackoff: time.Second, Multiplier: 3, MaxBackoff: 10 * time.Second}
+	want := []time.Duration{time.Second, 3 * time.Second, 9 * time.Second, 10 * time.Second, 10 * time.Second}
+	var d time.Duration
+	for i, w := range want {
+		if d = p.backoff(d); d != w {
+			t.Errorf("backoff #%d = %v, want %v", i+1, d, w)
+		}
+	}
+	p.Jitter = 0.25
+	for i := 0; i < 100; i++ {
+		if d := p.jitter(time.Second); d < 750*time.Millisecond || d > time.Second {
+			t.Errorf("jitter(1s) = %v", d)
+		}
+	}
+}
@@ -17,13 +17,16 @@ import (
 type serverCodec struct {
 	encmutex sync.Mutex    // protects enc
 	dec      *json.Decoder // for reading JSON values
+	jr       *jsonReader   // for reading JSON values if size is limited
 	enc      *json.Encoder // for writing JSON values
 	c        io.Closer
 	srv      *rpc.Server
 	ctx      context.Context
+	cfg      *serverConfig
 
 	// temporary work space
 	req serverRequest
+	id  *json.RawMessage // ID of req, nil for notification
 
 	// JSON-RPC clients can use arbitrary json values as request IDs.
 	// Package rpc expects uint64 request IDs.
@@ -36,7 +39,42 @@ type serverCodec struct {
 	pending map[uint64]*json.RawMessage
 }
 
-// NewServerCodec returns a new rpc.ServerCodec using JSON-RPC 2.0 on conn,
+// ServerOption configures Server created by NewServer.
+type ServerOption func(*serverConfig)
+
+type serverConfig struct {
+	jsonrpc2   bool
+	httpGet    bool
+	getMethods map[string]bool // nil if all methods allowed
+
+	maxRequestSize int64
+	maxBatchLength int
+
+	interceptors []Interceptor
+	registry     *Registry
+	aliases      map[string]string
+	mappers      []func(method string) string
+}
+
+func newServerConfig(opts []ServerOption) *serverConfig {
+	cfg := &serverConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return cfg
+}
+
+// ServerJSONRPC2 makes server speak JSON-RPC 2.0 instead of JSON-RPC 1.0:
+// requests must contain "jsonrpc":"2.0" member, requests without "id"
+// member are notifications and replies contain either "result" or
+// "error" member, but never both.
+func ServerJSONRPC2() ServerOption {
+	return func(cfg *serverConfig) {
+		cfg.jsonrpc2 = true
+	}
+}
+
+// NewServerCodec returns a new rpc.ServerCodec using JSON-RPC 1.0 on conn,
 // which will use srv to execute batch requests.
 //
 // If srv is nil then rpc.DefaultServer will be used.
@@ -46,36 +84,93 @@ type serverCodec struct {
 // your own object of type named "JSONRPC1" (same as used internally to
 // process batch requests) or you wanna use custom rpc server object
 // instead of rpc.DefaultServer to process requests on conn.
+//
+// Use NewServer to get codec configured by ServerOption.
 func NewServerCodec(conn io.ReadWriteCloser, srv *rpc.Server) rpc.ServerCodec {
+	return NewServer(srv).NewCodec(conn)
+}
+
+// NewServerCodecContext is NewServerCodec with given context provided
+// within parameters for compatible RPC methods.
+func NewServerCodecContext(ctx context.Context, conn io.ReadWriteCloser, srv *rpc.Server) rpc.ServerCodec {
+	return NewServer(srv).NewCodecContext(ctx, conn)
+}
+
+// Server serves JSON-RPC 1.0 (or 2.0, see ServerJSONRPC2) requests using
+// rpc.Server configured by ServerOption. It's also an http.Handler (see
+// HTTPHandler).
+type Server struct {
+	rpc *rpc.Server
+	cfg *serverConfig
+}
+
+// NewServer returns a new Server which will execute requests using srv
+// configured by given options.
+//
+// If srv is nil then rpc.DefaultServer will be used.
+func NewServer(srv *rpc.Server, opts ...ServerOption) *Server {
+	if srv == nil {
+		srv = rpc.DefaultServer
+	}
+	return &Server{rpc: srv, cfg: newServerConfig(opts)}
+}
+
+// NewCodec returns a new rpc.ServerCodec on conn, like NewServerCodec.
+// Codec must be served by server's rpc.Server.
+func (s *Server) NewCodec(conn io.ReadWriteCloser) rpc.ServerCodec {
+	return newServerCodec(context.Background(), conn, s.rpc, s.cfg)
+}
+
+// NewCodecContext is NewCodec with given context provided within
+// parameters for compatible RPC methods.
+func (s *Server) NewCodecContext(ctx context.Context, conn io.ReadWriteCloser) rpc.ServerCodec {
+	return newServerCodec(ctx, conn, s.rpc, s.cfg)
+}
+
+// ServeConn runs the server on a single connection, like ServeConn.
+func (s *Server) ServeConn(conn io.ReadWriteCloser) {
+	s.rpc.ServeCodec(s.NewCodec(conn))
+}
+
+// ServeConnContext is ServeConn with given context provided within
+// parameters for compatible RPC methods.
+func (s *Server) ServeConnContext(ctx context.Context, conn io.ReadWriteCloser) {
+	s.rpc.ServeCodec(s.NewCodecContext(ctx, conn))
+}
+
+func newServerCodec(ctx context.Context, conn io.ReadWriteCloser, srv *rpc.Server, cfg *serverConfig) *serverCodec {
 	if srv == nil {
 		srv = rpc.DefaultServer
 	}
 	srv.Register(JSONRPC1{})
-	return &serverCodec{
+	c := &serverCodec{
 		dec:     json.NewDecoder(conn),
 		enc:     json.NewEncoder(conn),
 		c:       conn,
 		srv:     srv,
-		ctx:     context.Background(),
+		ctx:     ctx,
+		cfg:     cfg,
+		req:     serverRequest{jsonrpc2: cfg.jsonrpc2},
 		pending: make(map[uint64]*json.RawMessage),
 	}
-}
-
-// NewServerCodecContext is NewServerCodec with given context provided
-// within parameters for compatible RPC methods.
-func NewServerCodecContext(ctx context.Context, conn io.ReadWriteCloser, srv *rpc.Server) rpc.ServerCodec {
-	codec := NewServerCodec(conn, srv)
-	codec.(*serverCodec).ctx = ctx
-	return codec
+	if cfg.maxRequestSize > 0 {
+		c.jr = newJSONReader(conn, cfg.maxRequestSize)
+		c.jr.stop = true // connection will be closed anyway
+	}
+	return c
 }
 
 type serverRequest struct {
-	Method string           `json:"method"`
-	Params *json.RawMessage `json:"params"`
-	ID     *json.RawMessage `json:"id"`
+	Version string           `json:"jsonrpc"`
+	Method  string           `json:"method"`
+	Params  *json.RawMessage `json:"params"`
+	ID      *json.RawMessage `json:"id"`
+
+	jsonrpc2 bool // validate as JSON-RPC 2.0 request
 }
 
 func (r *serverRequest) reset() {
+	r.Version = ""
 	r.Method = ""
 	r.Params = nil
 	r.ID = nil
@@ -98,7 +193,18 @@ func (r *serverRequest) UnmarshalJSON(raw []byte) error {
 	_, okID := o["id"]
 	_, okParams := o["params"]
 
-	if len(o) == 3 && !(okID || okParams) || len(o) == 4 && !(okID && okParams) || len(o) > 4 {
+	if r.jsonrpc2 {
+		for k := range o {
+			switch k {
+			case "jsonrpc", "method", "params", "id":
+			default:
+				return errors.New("bad request")
+			}
+		}
+		if o["jsonrpc"] == nil || r.Version != "2.0" {
+			return errors.New("bad request")
+		}
+	} else if len(o) == 3 && !(okID || okParams) || len(o) == 4 && !(okID && okParams) || len(o) > 4 {
 		return errors.New("bad request")
 	}
 
@@ -134,32 +240,90 @@ type serverResponse struct {
 	Error  interface{}      `json:"error"`
 }
 
+type serverResponse2 struct {
+	Version string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id"`
+	Result  interface{}      `json:"result,omitempty"`
+	Error   interface{}      `json:"error,omitempty"`
+}
+
+// response returns reply in format of protocol version used by cfg.
+func (cfg *serverConfig) response(id *json.RawMessage, result, err interface{}) interface{} {
+	if cfg.jsonrpc2 {
+		return serverResponse2{Version: "2.0", ID: id, Result: result, Error: err}
+	}
+	return serverResponse{ID: id, Result: result, Error: err}
+}
+
+// read reads next request. On error it sends error reply.
+func (c *serverCodec) read() (json.RawMessage, error) {
+	var raw json.RawMessage
+	if c.jr == nil {
+		if err := c.dec.Decode(&raw); err != nil {
+			c.encmutex.Lock()
+			c.enc.Encode(c.cfg.response(&null, nil, errParse))
+			c.encmutex.Unlock()
+			return nil, err
+		}
+		return raw, nil
+	}
+
+	raw, id, tooLarge, err := c.jr.next()
+	reply := errParse
+	switch {
+	case err != nil:
+	case tooLarge:
+		reply, err = errTooLarge, errors.New(errTooLarge.Message)
+	case !json.Valid(raw):
+		err = errors.New(errParse.Message)
+	default:
+		return raw, nil
+	}
+	replyID := &null
+	if tooLarge && id != nil && json.Valid(id) {
+		replyID = (*json.RawMessage)(&id)
+	}
+	c.encmutex.Lock()
+	c.enc.Encode(c.cfg.response(replyID, nil, reply))
+	c.encmutex.Unlock()
+	return nil, err
+}
+
 func (c *serverCodec) ReadRequestHeader(r *rpc.Request) (err error) {
 	// If return error:
 	// - codec will be closed
 	// So, try to send error reply to client before returning error.
-	var raw json.RawMessage
-	if err := c.dec.Decode(&raw); err != nil {
-		c.encmutex.Lock()
-		c.enc.Encode(serverResponse{ID: &null, Error: errParse})
-		c.encmutex.Unlock()
+	raw, err := c.read()
+	if err != nil {
 		return err
 	}
 
 	if len(raw) > 0 && raw[0] == '[' {
+		if c.cfg.maxBatchLength > 0 && batchTooLong(raw, c.cfg.maxBatchLength) {
+			c.encmutex.Lock()
+			c.enc.Encode(c.cfg.response(&null, nil, errTooLong))
+			c.encmutex.Unlock()
+			return errors.New(errTooLong.Message)
+		}
 		c.req.Method = "JSONRPC1.Batch"
 		c.req.Params = &raw
 		c.req.ID = &null
 	} else if err := json.Unmarshal(raw, &c.req); err != nil {
 		if err.Error() == "bad request" {
 			c.encmutex.Lock()
-			c.enc.Encode(serverResponse{ID: &null, Error: errRequest})
+			c.enc.Encode(c.cfg.response(&null, nil, errRequest))
 			c.encmutex.Unlock()
 		}
 		return err
 	}
 
-	r.ServiceMethod = c.req.Method
+	r.ServiceMethod = callMethod
+	if !c.cfg.needCall(c.req.Method) {
+		if c.req.Method != batchMethod {
+			c.req.Method = c.cfg.mapMethod(c.req.Method)
+		}
+		r.ServiceMethod = c.req.Method
+	}
 
 	// JSON request id can be any JSON value;
 	// RPC package expects uint64.  Translate to
@@ -167,7 +331,7 @@ func (c *serverCodec) ReadRequestHeader(r *rpc.Request) (err error) {
 	c.mutex.Lock()
 	c.seq++
 	c.pending[c.seq] = c.req.ID
-	c.req.ID = nil
+	c.id, c.req.ID = c.req.ID, nil
 	r.Seq = c.seq
 	c.mutex.Unlock()
 
@@ -180,23 +344,37 @@ func (c *serverCodec) ReadRequestBody(x interface{}) error {
 	if x == nil {
 		return nil
 	}
+	if arg, ok := x.(*CallArg); ok && c.cfg.needCall(c.req.Method) {
+		arg.srv = c.srv
+		arg.cfg = c.cfg
+		arg.call = &ServerCall{Method: c.req.Method}
+		arg.call.SetContext(c.ctx)
+		if c.req.Params != nil {
+			arg.call.Params = *c.req.Params
+		}
+		if c.id != nil {
+			arg.call.ID = *c.id
+		}
+		return nil
+	}
 	if x, ok := x.(WithContext); ok {
 		x.SetContext(c.ctx)
 	}
 	if c.req.Params == nil {
-		return nil
+		return unmarshalParams(nil, x)
 	}
 	if c.req.Method == "JSONRPC1.Batch" {
 		arg := x.(*BatchArg)
 		arg.srv = c.srv
+		arg.cfg = c.cfg
 		if err := json.Unmarshal(*c.req.Params, &arg.reqs); err != nil {
 			return NewError(errParams.Code, err.Error())
 		}
 		if len(arg.reqs) == 0 {
 			return errRequest
 		}
-	} else if err := json.Unmarshal(*c.req.Params, x); err != nil {
-		return NewError(errParams.Code, err.Error())
+	} else if err := unmarshalParams(*c.req.Params, x); err != nil {
+		return err
 	}
 	return nil
 }
@@ -231,12 +409,14 @@ func (c *serverCodec) WriteResponse(r *rpc.Response, x interface{}) error {
 		return nil
 	}
 
-	resp := serverResponse{ID: b, Error: nil}
-	if r.Error == "" {
+	var result, resperr interface{}
+	if reply, ok := x.(*CallReply); ok && r.Error == "" {
+		result, resperr = reply.encode()
+	} else if r.Error == "" {
 		if x == nil {
-			resp.Result = &null
+			result = &null
 		} else {
-			resp.Result = x
+			result = x
 		}
 	} else if r.Error[0] == '{' && r.Error[len(r.Error)-1] == '}' {
 		// Well… this check for '{'…'}' isn't too strict, but I
@@ -244,22 +424,22 @@ func (c *serverCodec) WriteResponse(r *rpc.Response, x interface{}) error {
 		// can force sending wrong reply or many replies instead
 		// of one) and normal errors won't be formatted this way.
 		raw := json.RawMessage(r.Error)
-		resp.Error = &raw
+		resperr = &raw
 	} else {
 		raw := json.RawMessage(newError(r.Error).Error())
-		resp.Error = &raw
+		resperr = &raw
 	}
 
 	c.encmutex.Lock()
 	defer c.encmutex.Unlock()
-	return c.enc.Encode(resp)
+	return c.enc.Encode(c.cfg.response(b, result, resperr))
 }
 
 func (c *serverCodec) Close() error {
 	return c.c.Close()
 }
 
-// ServeConn runs the JSON-RPC 2.0 server on a single connection.
+// ServeConn runs the JSON-RPC 1.0 server on a single connection.
 // ServeConn blocks, serving the connection until the client hangs up.
 // The caller typically invokes ServeConn in a go statement.
 func ServeConn(conn io.ReadWriteCloser) {